@@ -42,6 +42,10 @@ type AutoVirtualDCStatus struct {
 
 	// Next stop time of VirtualDC's schedule.
 	NextStopTime *metav1.Time `json:"nextStopTime,omitempty"`
+
+	// The time when the job of VirtualDC was found cancelled or terminated by the shutdown of the runner.
+	// It is cleared when the job runs again or VirtualDC is recreated.
+	JobStoppedTime *metav1.Time `json:"jobStoppedTime,omitempty"`
 }
 
 //+kubebuilder:resource:shortName=avdc
@@ -41,10 +41,117 @@ type VirtualDCSpec struct {
 	//+kubebuiler:validation:Optional
 	Command []string `json:"command,omitempty"`
 
+	// Jobs run in order in the runner pod after bootstrapping dctest.
+	// Each job appears separately in the status of the runner pod.
+	// This field cannot be used with command, which is a shorthand for a job named "user_defined_command".
+	//+kubebuilder:validation:Optional
+	Jobs []Job `json:"jobs,omitempty"`
+
+	// Environment variables of the runner container, which are set in addition to NECO_BRANCH and NECO_APPS_BRANCH.
+	// The names prefixed with NYAMBER_ are reserved for the variables set by the runner.
+	// The Secrets and the ConfigMaps referenced in valueFrom must be in the namespace of VirtualDC.
+	// The controller copies them to the namespace of the runner pod, and the runner container refers to the copies.
+	//+kubebuilder:validation:Optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Sources of environment variables of the runner container.
+	// The referenced Secrets and ConfigMaps are copied in the same way as those of env.
+	//+kubebuilder:validation:Optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// Compute resources of the runner container.
+	// They override the resources of the container in the pod template for each resource name.
+	// The requests must not exceed the limits, and neither may exceed the maximum configured in the controller.
 	//+kubebuiler:validation:Optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
-	// Volume for ConfigMap
+	// Node selector of the runner pod.
+	// The labels are added to the node selector of the pod template, overriding the same keys.
+	//+kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Affinity of the runner pod.
+	// Each of the node affinity, the pod affinity and the pod anti-affinity replaces that of the pod template if it is set.
+	//+kubebuilder:validation:Optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Tolerations of the runner pod, which are added to the tolerations of the pod template.
+	//+kubebuilder:validation:Optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Timeouts of jobs run in the runner pod, keyed by the job name.
+	// Available job names are "neco_bootstrap", "neco_apps_bootstrap", "user_defined_command" and the names in jobs,
+	// and the job must be run in the runner pod.
+	// A job which runs longer than its timeout is killed and reported as TimedOut.
+	//+kubebuilder:validation:Optional
+	JobTimeouts map[string]metav1.Duration `json:"jobTimeouts,omitempty"`
+
+	// Numbers of retries of jobs run in the runner pod, keyed by the job name.
+	// Available job names are the same as jobTimeouts.
+	// A job which fails or times out is retried with exponential backoff.
+	//+kubebuilder:validation:Optional
+	JobRetries map[string]int32 `json:"jobRetries,omitempty"`
+
+	// Policy to handle the job which was running when the runner container restarted.
+	// "Resume" runs the job again, and "Interrupt" marks the job as Interrupted and skips the jobs after it.
+	// If this field is empty, the job runs again.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum=Resume;Interrupt
+	ResumePolicy string `json:"resumePolicy,omitempty"`
+
+	// Name of the job which must complete before the runner pod becomes ready.
+	// The job must be run in the runner pod.
+	// If this field is empty, the runner pod becomes ready once the entrypoint starts.
+	//+kubebuilder:validation:Optional
+	ReadyJob string `json:"readyJob,omitempty"`
+
+	// Paths of the test reports written by jobs run in the runner pod, keyed by the job name.
+	// Available job names are the same as jobTimeouts.
+	// A report is a JUnit XML or Ginkgo JSON file, and its summary appears in status.testSummary.
+	// A relative path is relative to the working directory of the runner container.
+	//+kubebuilder:validation:Optional
+	TestReports map[string]string `json:"testReports,omitempty"`
+
+	// Enable the web terminal of the runner pod if true.
+	// The token to access the terminal and the other API of the runner pod is stored in the Secret named "<VirtualDC name>-token" in the namespace of VirtualDC.
+	//+kubebuilder:validation:Optional
+	Terminal bool `json:"terminal,omitempty"`
+
+	// ConfigMaps in the namespace of VirtualDC to mount in the runner container.
+	// The controller copies them to the namespace of the runner pod and keeps the copies up to date,
+	// so that the changes of the ConfigMaps propagate to the mounted files.
+	//+kubebuilder:validation:Optional
+	ConfigMapMounts []ConfigMapMount `json:"configMapMounts,omitempty"`
+}
+
+// Job is a job run in the runner pod.
+type Job struct {
+	// Name of the job, which must not be "neco_bootstrap" or "neco_apps_bootstrap".
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z][-_a-zA-Z0-9]*$`
+	Name string `json:"name"`
+
+	// Command to run and its arguments.
+	//+kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+}
+
+// ConfigMapMount is a ConfigMap mounted in the runner container.
+type ConfigMapMount struct {
+	// Name of the ConfigMap in the namespace of VirtualDC.
+	Name string `json:"name"`
+
+	// Absolute path in the runner container to mount the ConfigMap at.
+	MountPath string `json:"mountPath"`
+
+	// Keys of the ConfigMap to mount and their paths relative to mountPath.
+	// If this field is empty, all the keys are mounted as files named after the keys.
+	//+kubebuilder:validation:Optional
+	Items []corev1.KeyToPath `json:"items,omitempty"`
+
+	// Mount an empty directory if the ConfigMap does not exist.
+	// If this field is false, the controller does not create the runner pod until the ConfigMap is created.
+	//+kubebuilder:validation:Optional
+	Optional bool `json:"optional,omitempty"`
 }
 
 // VirtualDCStatus defines the observed state of VirtualDC
@@ -52,6 +159,30 @@ type VirtualDCStatus struct {
 	// Conditions is an array of conditions.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// TestSummary is the summary of the test report written by the last job which has one.
+	// +optional
+	TestSummary *TestSummary `json:"testSummary,omitempty"`
+}
+
+// TestSummary is the summary of a test report written by a job.
+type TestSummary struct {
+	// Job is the name of the job which wrote the test report.
+	Job string `json:"job"`
+
+	// Passed is the number of the passed tests.
+	Passed int32 `json:"passed"`
+
+	// Failed is the number of the failed tests.
+	Failed int32 `json:"failed"`
+
+	// Skipped is the number of the skipped tests.
+	Skipped int32 `json:"skipped"`
+
+	// FailedTests is the names of the failed tests.
+	// Only the first 10 names are recorded.
+	// +optional
+	FailedTests []string `json:"failedTests,omitempty"`
 }
 
 const (
@@ -64,17 +195,25 @@ const (
 const ReasonOK string = "OK"
 
 const (
-	ReasonPodCreatedConflict       string = "Conflict"
-	ReasonPodCreatedFailed         string = "Failed"
-	ReasonPodCreatedTemplateError  string = "TemplateError"
-	ReasonPodAvailableNotAvailable string = "NotAvailable"
-	ReasonPodAvailableNotExists    string = "NotExists"
-	ReasonPodAvailableNotScheduled string = "NotScheduled"
-	ReasonServiceCreatedConflict   string = "Conflict"
-	ReasonServiceCreatedFailed     string = "Failed"
-	ReasonPodJobCompletedPending   string = "Pending"
-	ReasonPodJobCompletedRunning   string = "Running"
-	ReasonPodJobCompletedFailed    string = "Failed"
+	ReasonPodCreatedConflict         string = "Conflict"
+	ReasonPodCreatedFailed           string = "Failed"
+	ReasonPodCreatedTemplateError    string = "TemplateError"
+	ReasonPodCreatedInvalidResources string = "InvalidResources"
+	ReasonPodCreatedCopyFailed       string = "CopyFailed"
+	ReasonPodCreatedInvalidMounts    string = "InvalidMounts"
+	ReasonPodAvailableNotAvailable   string = "NotAvailable"
+	ReasonPodAvailableNotExists      string = "NotExists"
+	ReasonPodAvailableNotScheduled   string = "NotScheduled"
+	ReasonServiceCreatedConflict     string = "Conflict"
+	ReasonServiceCreatedFailed       string = "Failed"
+	ReasonPodJobCompletedPending     string = "Pending"
+	ReasonPodJobCompletedRunning     string = "Running"
+	ReasonPodJobCompletedFailed      string = "Failed"
+	ReasonPodJobCompletedTimedOut    string = "TimedOut"
+	ReasonPodJobCompletedSkipped     string = "Skipped"
+	ReasonPodJobCompletedCancelled   string = "Cancelled"
+	ReasonPodJobCompletedInterrupted string = "Interrupted"
+	ReasonPodJobCompletedTerminated  string = "Terminated"
 )
 
 //+kubebuilder:object:root=true
@@ -83,6 +222,9 @@ const (
 //+kubebuilder:printcolumn:name="PODAVAILABLE",type="string",JSONPath=".status.conditions[?(@.type=='PodAvailable')].status"
 //+kubebuilder:printcolumn:name="JOBSTATUS",type="string",JSONPath=".status.conditions[?(@.type=='PodJobCompleted')].reason"
 //+kubebuilder:printcolumn:name="JOBNAME",type="string",JSONPath=".status.conditions[?(@.type=='PodJobCompleted')].message"
+//+kubebuilder:printcolumn:name="PASSED",type="integer",JSONPath=".status.testSummary.passed"
+//+kubebuilder:printcolumn:name="FAILED",type="integer",JSONPath=".status.testSummary.failed"
+//+kubebuilder:printcolumn:name="SKIPPED",type="integer",JSONPath=".status.testSummary.skipped",priority=1
 
 // VirtualDC is the Schema for the virtualdcs API
 type VirtualDC struct {
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/cybozu-go/nyamber/pkg/entrypoint"
+	"github.com/spf13/cobra"
+)
+
+var reportStep string
+var reportPercent int
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the progress of the running job",
+	Long: `Report the progress of the running job to the entrypoint.
+
+This command is meant to be called from a job run by the entrypoint,
+which gives the job name, the entrypoint URL and the token to access it
+by environment variables.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobName := os.Getenv(entrypoint.JobNameEnvName)
+		runnerURL := os.Getenv(entrypoint.RunnerURLEnvName)
+		if jobName == "" || runnerURL == "" {
+			return errors.New("this command must be called from a job run by the entrypoint")
+		}
+
+		progress := entrypoint.Progress{Step: reportStep}
+		if cmd.Flags().Changed("percent") {
+			progress.Percent = &reportPercent
+		}
+		return entrypoint.ReportProgress(runnerURL, os.Getenv(entrypoint.TokenEnvName), jobName, progress)
+	},
+}
+
+func init() {
+	fs := reportCmd.Flags()
+	fs.StringVar(&reportStep, "step", "", "Name of the current step of the job.")
+	fs.IntVar(&reportPercent, "percent", 0, "Progress of the job in percent.")
+	reportCmd.MarkFlagRequired("step")
+	rootCmd.AddCommand(reportCmd)
+}
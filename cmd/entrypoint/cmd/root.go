@@ -3,8 +3,8 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/cybozu-go/nyamber/pkg/constants"
 	"github.com/cybozu-go/nyamber/pkg/entrypoint"
@@ -16,47 +16,193 @@ import (
 )
 
 var listenAddr string
+var jobsFile string
+var jobTimeouts map[string]string
+var jobRetries map[string]int
+var retryBackoff time.Duration
+var dependencies []string
+var finallyJobs []string
+var testReports map[string]string
+var runnerOptions entrypoint.RunnerOptions
 var log logr.Logger
-var reJobName = regexp.MustCompile("^[a-zA-Z][-_a-zA-Z0-9]*$")
 
 var rootCmd = &cobra.Command{
-	Use:          "entrypoint <JOB_NAME:COMMAND>...",
+	Use:          "entrypoint [<JOB_NAME:COMMAND>...]",
 	Short:        "DC test pod entrypoint",
 	Long:         "DC test pod entrypoint",
-	Args:         cobra.MinimumNArgs(1),
+	Args:         cobra.ArbitraryArgs,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		jobs := make([]entrypoint.Job, 0, len(args))
-		for _, job := range args {
-			split := strings.Split(job, ":")
-			if len(split) != 2 {
-				return errors.New("wrong job format")
+		var jobs []entrypoint.Job
+		switch {
+		case jobsFile != "" && len(args) > 0:
+			return errors.New("jobs must be given by either --jobs-file or arguments")
+		case jobsFile != "":
+			var err error
+			jobs, err = entrypoint.LoadJobsFile(jobsFile)
+			if err != nil {
+				return err
 			}
-			jobName := split[0]
-			if !reJobName.MatchString(jobName) {
-				return errors.New("unexpected characters in JOB_NAME")
+		case len(args) > 0:
+			var err error
+			jobs, err = parseJobArgs(args)
+			if err != nil {
+				return err
 			}
+		default:
+			return errors.New("no jobs are given")
+		}
 
-			command := split[1]
-			if len(command) < 1 {
-				return errors.New("COMMAND is empty")
-			}
-			splittedCmd := strings.Split(command, " ")
-
-			jobs = append(jobs, entrypoint.Job{
-				Name:    jobName,
-				Command: splittedCmd[0],
-				Args:    splittedCmd[1:],
-			})
+		if err := setTimeouts(jobs, jobTimeouts); err != nil {
+			return err
+		}
+		if err := setRetries(jobs, jobRetries, retryBackoff); err != nil {
+			return err
+		}
+		if err := setDependencies(jobs, dependencies); err != nil {
+			return err
+		}
+		if err := setFinally(jobs, finallyJobs); err != nil {
+			return err
+		}
+		if err := setTestReports(jobs, testReports); err != nil {
+			return err
 		}
 
-		runner := entrypoint.NewRunner(listenAddr, log, jobs)
+		runner, err := entrypoint.NewRunner(listenAddr, log, jobs, runnerOptions)
+		if err != nil {
+			return err
+		}
 		well.Go(runner.Run)
 		well.Stop()
-		return well.Wait()
+		if err := well.Wait(); err != nil && !well.IsSignaled(err) {
+			return err
+		}
+		return nil
 	},
 }
 
+// parseJobArgs parses jobs given as JOB_NAME:COMMAND arguments.
+// The command is split by spaces, so arguments cannot contain spaces.
+// Use a jobs file to give such arguments.
+func parseJobArgs(args []string) ([]entrypoint.Job, error) {
+	jobs := make([]entrypoint.Job, 0, len(args))
+	for _, job := range args {
+		jobName, command, ok := strings.Cut(job, ":")
+		if !ok {
+			return nil, errors.New("wrong job format")
+		}
+		if !entrypoint.IsValidJobName(jobName) {
+			return nil, errors.New("unexpected characters in JOB_NAME")
+		}
+		if len(command) < 1 {
+			return nil, errors.New("COMMAND is empty")
+		}
+		splittedCmd := strings.Split(command, " ")
+
+		jobs = append(jobs, entrypoint.Job{
+			Name:    jobName,
+			Command: splittedCmd[0],
+			Args:    splittedCmd[1:],
+		})
+	}
+	return jobs, nil
+}
+
+func findJob(jobs []entrypoint.Job, name string) (*entrypoint.Job, error) {
+	for i := range jobs {
+		if jobs[i].Name == name {
+			return &jobs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown job %s", name)
+}
+
+func setTimeouts(jobs []entrypoint.Job, timeouts map[string]string) error {
+	for name, value := range timeouts {
+		job, err := findJob(jobs, name)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeout for job %s: %w", name, err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("timeout for job %s must be positive", name)
+		}
+		job.Timeout = timeout
+	}
+	return nil
+}
+
+func setRetries(jobs []entrypoint.Job, retries map[string]int, backoff time.Duration) error {
+	if backoff <= 0 {
+		return errors.New("retry backoff must be positive")
+	}
+	for i := range jobs {
+		if jobs[i].RetryBackoff == 0 {
+			jobs[i].RetryBackoff = backoff
+		}
+	}
+	for name, n := range retries {
+		job, err := findJob(jobs, name)
+		if err != nil {
+			return fmt.Errorf("invalid retries: %w", err)
+		}
+		if n < 0 {
+			return fmt.Errorf("retries for job %s must not be negative", name)
+		}
+		job.Retries = n
+	}
+	return nil
+}
+
+func setDependencies(jobs []entrypoint.Job, dependencies []string) error {
+	for _, dep := range dependencies {
+		name, depName, ok := strings.Cut(dep, "=")
+		if !ok {
+			return fmt.Errorf("dependency must be formatted as JOB_NAME=DEPENDENCY: %s", dep)
+		}
+		job, err := findJob(jobs, name)
+		if err != nil {
+			return fmt.Errorf("invalid dependency: %w", err)
+		}
+		if job.DependsOn == nil {
+			job.DependsOn = []string{}
+		}
+		if depName != "" {
+			job.DependsOn = append(job.DependsOn, depName)
+		}
+	}
+	return nil
+}
+
+func setFinally(jobs []entrypoint.Job, names []string) error {
+	for _, name := range names {
+		job, err := findJob(jobs, name)
+		if err != nil {
+			return fmt.Errorf("invalid finally job: %w", err)
+		}
+		job.Finally = true
+	}
+	return nil
+}
+
+func setTestReports(jobs []entrypoint.Job, reports map[string]string) error {
+	for name, path := range reports {
+		job, err := findJob(jobs, name)
+		if err != nil {
+			return fmt.Errorf("invalid test report: %w", err)
+		}
+		if path == "" {
+			return fmt.Errorf("test report path for job %s is empty", name)
+		}
+		job.TestReport = path
+	}
+	return nil
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
@@ -64,6 +210,21 @@ func Execute() error {
 func init() {
 	fs := rootCmd.Flags()
 	fs.StringVar(&listenAddr, "listen-address", fmt.Sprintf(":%d", constants.ListenPort), "Listening address and port.")
+	fs.StringVar(&jobsFile, "jobs-file", "", "Path to a YAML or JSON file defining the jobs. This cannot be used with JOB_NAME:COMMAND arguments.")
+	fs.StringToStringVar(&jobTimeouts, "job-timeout", nil, "Timeout of jobs in JOB_NAME=DURATION form, e.g. neco_bootstrap=2h. Jobs without a timeout run until they finish.")
+	fs.StringToIntVar(&jobRetries, "job-retries", nil, "Number of retries of jobs in JOB_NAME=N form, e.g. neco_bootstrap=2. Jobs are not retried by default.")
+	fs.DurationVar(&retryBackoff, "retry-backoff", entrypoint.DefaultRetryBackoff, "Duration to wait before the first retry of a job. The duration doubles for every retry.")
+	fs.StringArrayVar(&dependencies, "depends-on", nil, "Dependency of a job in JOB_NAME=DEPENDENCY form. Repeat this to add more dependencies. JOB_NAME= makes the job start without waiting for any job. Jobs without this depend on the previous job.")
+	fs.StringSliceVar(&finallyJobs, "finally", nil, "Names of the jobs which run after all the other jobs have finished, whether they have succeeded or not.")
+	fs.StringToStringVar(&testReports, "test-report", nil, "Path of the JUnit XML or Ginkgo JSON report written by a job in JOB_NAME=PATH form. The report is summarized in the status of the job.")
+	fs.StringVar(&runnerOptions.StateDir, "state-dir", "", "Directory to record the job states. If this is set, the job states are restored from the directory when the entrypoint restarts.")
+	fs.StringVar(&runnerOptions.ResumePolicy, "resume-policy", entrypoint.ResumePolicyResume, fmt.Sprintf("Policy to handle the jobs which were running when the entrypoint restarted. %q runs them again, and %q marks them as Interrupted.", entrypoint.ResumePolicyResume, entrypoint.ResumePolicyInterrupt))
+	fs.StringVar(&runnerOptions.ReadyJob, "ready-job", "", "Name of the job which must complete before the entrypoint reports ready at /readyz. If this is empty, the entrypoint is ready once its HTTP server is up.")
+	fs.StringVar(&runnerOptions.TokenFile, "token-file", "", "File which has the token to access the API. If this is set, the API other than /healthz, /readyz and /metrics requires the token as a bearer token.")
+	fs.BoolVar(&runnerOptions.Terminal, "terminal", false, "Enable the terminal API. This requires --token-file.")
+	fs.StringVar(&runnerOptions.TerminalShell, "terminal-shell", entrypoint.DefaultTerminalShell, "Shell run by the terminal API.")
+	fs.StringVar(&runnerOptions.NotifyURL, "notify-url", "", "URL to be notified of the changes of the job states by a POST request. The token in --token-file is sent as a bearer token.")
+	fs.DurationVar(&runnerOptions.GracePeriod, "grace-period", entrypoint.DefaultGracePeriod, "Time to wait for a job to exit after SIGTERM before killing it with SIGKILL. Jobs receive SIGTERM when they are cancelled, time out or the entrypoint is shutting down.")
 	zapLog, err := zap.NewDevelopment()
 	if err != nil {
 		panic(fmt.Sprintf("who watches the watchmen (%v)?", err))
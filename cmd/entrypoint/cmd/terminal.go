@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/cybozu-go/nyamber/pkg/entrypoint"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var terminalCmd = &cobra.Command{
+	Use:   "terminal RUNNER_URL",
+	Short: "Open a shell in the runner pod",
+	Long: `Open a shell in the runner pod through the terminal API of the entrypoint.
+
+RUNNER_URL is the URL of the runner API, e.g. http://<vdc-name>.nyamber-runner.
+The token to access the API is read from the ` + entrypoint.TokenEnvName + ` environment variable.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := os.Getenv(entrypoint.TokenEnvName)
+		if token == "" {
+			return fmt.Errorf("%s is not set", entrypoint.TokenEnvName)
+		}
+		u, err := terminalURL(args[0])
+		if err != nil {
+			return err
+		}
+		return runTerminal(u, token)
+	},
+}
+
+// terminalURL returns the websocket URL of the terminal API of the runner.
+func terminalURL(runnerURL string) (string, error) {
+	u, err := url.Parse(runnerURL)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return u.JoinPath(constants.TerminalEndPoint).String(), nil
+}
+
+func runTerminal(u, token string) error {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	conn, resp, err := websocket.DefaultDialer.Dial(u, header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("failed to connect to the terminal: %s", resp.Status)
+		}
+		return err
+	}
+	defer conn.Close()
+
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+
+		resize := func() {
+			cols, rows, err := term.GetSize(fd)
+			if err != nil {
+				return
+			}
+			data, _ := json.Marshal(entrypoint.TerminalSize{Cols: uint16(cols), Rows: uint16(rows)})
+			conn.WriteMessage(websocket.TextMessage, data)
+		}
+		resize()
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer signal.Stop(winch)
+		go func() {
+			for range winch {
+				resize()
+			}
+		}()
+	}
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code == websocket.CloseNormalClosure {
+				fmt.Fprintf(os.Stderr, "\r\n%s\r\n", strings.TrimSpace(closeErr.Text))
+				return nil
+			}
+			return err
+		}
+		if _, err := os.Stdout.Write(data); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			return err
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(terminalCmd)
+}
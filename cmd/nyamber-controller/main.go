@@ -18,6 +18,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"time"
 
@@ -29,11 +30,14 @@ import (
 	"github.com/cybozu-go/nyamber/controllers"
 	"github.com/cybozu-go/nyamber/hooks"
 	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/cybozu-go/nyamber/pkg/resources"
 	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -59,6 +63,10 @@ func main() {
 	var probeAddr string
 	var podNamespace string
 	var requeueInterval time.Duration
+	var notifyAddr string
+	var notifyURL string
+	var pollInterval time.Duration
+	var maxResourcesFlag string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +74,12 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&podNamespace, "pod-namespace", constants.PodNamespace, "A Namespace to deploy VirtualDC pod.")
 	flag.DurationVar(&requeueInterval, "requeue-interval", time.Minute, "Requeue interval on waiting pod-job of VirtualDC to be completed")
+	flag.StringVar(&notifyAddr, "notify-bind-address", fmt.Sprintf(":%d", constants.NotifyPort), "The address the endpoint to receive notifications from VirtualDC pods binds to.")
+	flag.StringVar(&notifyURL, "notify-url",
+		fmt.Sprintf("http://%s.%s.svc:%d", constants.NotifyServiceName, constants.ControllerNamespace, constants.NotifyPort),
+		"The URL for VirtualDC pods to notify the changes of the job states. If this is empty, the job states are only polled.")
+	flag.DurationVar(&pollInterval, "poll-interval", time.Minute, "Interval to poll the job states of VirtualDC pods as a fallback for lost notifications")
+	flag.StringVar(&maxResourcesFlag, "max-resources", "", "The maximum of the resource requests and limits of the runner container in NAME=QUANTITY form separated by commas, e.g. cpu=16,memory=64Gi")
 	opts := zap.Options{
 		Development: true,
 		TimeEncoder: zapcore.ISO8601TimeEncoder,
@@ -75,6 +89,12 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	maxResources, err := resources.Parse(maxResourcesFlag)
+	if err != nil {
+		setupLog.Error(err, "invalid --max-resources")
+		os.Exit(1)
+	}
+
 	webHookServer := webhook.NewServer(webhook.Options{
 		Port: 9443,
 	})
@@ -85,6 +105,13 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "f8a5f6ae.nyamber.cybozu.io",
+		// Read Secrets directly from the API server instead of caching all the Secrets in the cluster.
+		// The controller reads only the Secrets of VirtualDCs, which are a small part of them.
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -92,15 +119,28 @@ func main() {
 	}
 
 	client := mgr.GetClient()
+	jobProcessManager := controllers.NewJobProcessManager(ctrl.Log, client, podNamespace, pollInterval)
 	if err = (&controllers.VirtualDCReconciler{
 		Client:            client,
 		Scheme:            mgr.GetScheme(),
 		PodNamespace:      podNamespace,
-		JobProcessManager: controllers.NewJobProcessManager(ctrl.Log, client, podNamespace),
+		JobProcessManager: jobProcessManager,
+		NotifyURL:         notifyURL,
+		MaxResources:      maxResources,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VirtualDC")
 		os.Exit(1)
 	}
+	if err = mgr.Add(&controllers.NotifyServer{
+		Addr:              notifyAddr,
+		Client:            client,
+		PodNamespace:      podNamespace,
+		JobProcessManager: jobProcessManager,
+		Log:               ctrl.Log.WithName("NotifyServer"),
+	}); err != nil {
+		setupLog.Error(err, "unable to add notify server")
+		os.Exit(1)
+	}
 	if err = (&controllers.AutoVirtualDCReconciler{
 		Client:          mgr.GetClient(),
 		Scheme:          mgr.GetScheme(),
@@ -110,11 +150,11 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "AutoVirtualDC")
 		os.Exit(1)
 	}
-	if err = hooks.SetupVirtualDCWebhookWithManager(mgr); err != nil {
+	if err = hooks.SetupVirtualDCWebhookWithManager(mgr, maxResources); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "VirtualDC")
 		os.Exit(1)
 	}
-	if err = hooks.SetupAutoVirtualDCWebhookWithManager(mgr); err != nil {
+	if err = hooks.SetupAutoVirtualDCWebhookWithManager(mgr, maxResources); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "AutoVirtualDC")
 		os.Exit(1)
 	}
@@ -137,6 +137,7 @@ func (r *AutoVirtualDCReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	} else {
 		logger.Info("vdc is deleted successfully")
 	}
+	avdc.Status.JobStoppedTime = nil
 
 	if err := r.updateStatusTime(ctx, avdc); err != nil {
 		logger.Error(err, "failed to update avdc status")
@@ -203,11 +204,25 @@ func (r *AutoVirtualDCReconciler) reconcileVirtualDC(ctx context.Context, avdc *
 	}
 
 	isJobFinished, reason := isJobFinished(vdc)
-	if !isJobFinished {
+	if reason != nyamberv1beta1.ReasonPodJobCompletedCancelled && reason != nyamberv1beta1.ReasonPodJobCompletedTerminated {
+		avdc.Status.JobStoppedTime = nil
+	} else if avdc.Status.JobStoppedTime == nil {
+		now := metav1.NewTime(r.Now())
+		avdc.Status.JobStoppedTime = &now
+	}
+	switch {
+	case reason == nyamberv1beta1.ReasonPodJobCompletedCancelled:
+		return r.reconcileCancelledVirtualDC(ctx, avdc, vdc)
+	case reason == nyamberv1beta1.ReasonPodJobCompletedTerminated:
+		// The runner container may be restarting, and then it resumes the terminated job.
+		// So VDC is regarded as failed only if the job stays terminated for the grace period.
+		if elapsed := r.Sub(r.Now(), avdc.Status.JobStoppedTime.Time); elapsed < terminatedGracePeriod {
+			return ctrl.Result{RequeueAfter: terminatedGracePeriod - elapsed}, nil
+		}
+	case !isJobFinished:
 		// requeue to recheck VDC condition when VDC is not ready
 		return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
-	}
-	if reason == nyamberv1beta1.ReasonOK {
+	case reason == nyamberv1beta1.ReasonOK:
 		return ctrl.Result{}, nil
 	}
 
@@ -230,11 +245,15 @@ func (r *AutoVirtualDCReconciler) reconcileVirtualDC(ctx context.Context, avdc *
 	if err := r.Delete(ctx, vdc); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to delete VirtualDC: %w", err)
 	}
-	logger.Info("deleted vdc to recreate it. Reason: jobCompletedFailed")
+	avdc.Status.JobStoppedTime = nil
+	logger.Info("deleted vdc to recreate it", "reason", reason)
 
 	return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
 }
 
+// terminatedGracePeriod is the time to wait for the runner container to restart and resume the job terminated by its shutdown.
+const terminatedGracePeriod = 10 * time.Minute
+
 type RealClock struct{}
 
 func (r *RealClock) Now() time.Time {
@@ -245,11 +264,42 @@ func (r *RealClock) Sub(a, b time.Time) time.Duration {
 	return a.Sub(b)
 }
 
-// isJobFinished returns if Reason of VDC PodJobCompleted is Completed or Failed and its Reason.
+// reconcileCancelledVirtualDC keeps VDC whose job was cancelled by the owner, because the owner may rerun the job.
+// VDC is recreated if the job stays cancelled for timeoutDuration.
+// If timeoutDuration is not set, VDC is kept until the stop schedule, and it is reconciled again when its status changes.
+func (r *AutoVirtualDCReconciler) reconcileCancelledVirtualDC(ctx context.Context, avdc *nyamberv1beta1.AutoVirtualDC, vdc *nyamberv1beta1.VirtualDC) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if avdc.Spec.TimeoutDuration == "" {
+		return ctrl.Result{}, nil
+	}
+	timeoutDuration, err := time.ParseDuration(avdc.Spec.TimeoutDuration)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if elapsed := r.Sub(r.Now(), avdc.Status.JobStoppedTime.Time); elapsed < timeoutDuration {
+		return ctrl.Result{RequeueAfter: timeoutDuration - elapsed}, nil
+	}
+
+	if err := r.Delete(ctx, vdc); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete VirtualDC: %w", err)
+	}
+	avdc.Status.JobStoppedTime = nil
+	logger.Info("deleted vdc to recreate it", "reason", nyamberv1beta1.ReasonPodJobCompletedCancelled)
+	return ctrl.Result{RequeueAfter: r.RequeueInterval}, nil
+}
+
+// isJobFinished returns if Reason of VDC PodJobCompleted is Completed, Failed, TimedOut or Interrupted and its Reason.
+// Cancelled and Terminated are not regarded as finished, because the job may run again by the owner or a restart of the runner.
 func isJobFinished(vdc *nyamberv1beta1.VirtualDC) (bool, string) {
 	jobCondition := meta.FindStatusCondition(vdc.Status.Conditions, nyamberv1beta1.TypePodJobCompleted)
 	if jobCondition == nil {
 		return false, ""
 	}
-	return jobCondition.Reason == nyamberv1beta1.ReasonOK || jobCondition.Reason == nyamberv1beta1.ReasonPodJobCompletedFailed, jobCondition.Reason
+	switch jobCondition.Reason {
+	case nyamberv1beta1.ReasonOK, nyamberv1beta1.ReasonPodJobCompletedFailed, nyamberv1beta1.ReasonPodJobCompletedTimedOut,
+		nyamberv1beta1.ReasonPodJobCompletedInterrupted:
+		return true, jobCondition.Reason
+	}
+	return false, jobCondition.Reason
 }
@@ -310,6 +310,25 @@ var _ = Describe("AutoVirtualDC controller", func() {
 			g.Expect(vdc.UID).To(Equal(previousVdcUid))
 		}).Should(Succeed())
 
+		By("setting vdc's status to be cancelled")
+		clock.Step(time.Second)
+		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
+			Type:   nyamberv1beta1.TypePodJobCompleted,
+			Status: metav1.ConditionFalse,
+			Reason: nyamberv1beta1.ReasonPodJobCompletedCancelled,
+		})
+		err = k8sClient.Status().Update(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking vdc is not recreated")
+		clock.Step(time.Second)
+		Consistently(func(g Gomega) {
+			vdc := &nyamberv1beta1.VirtualDC{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, vdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(vdc.UID).To(Equal(previousVdcUid))
+		}).Should(Succeed())
+
 		By("setting vdc's status to be completed")
 		clock.Step(time.Second)
 		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
@@ -660,6 +679,99 @@ var _ = Describe("AutoVirtualDC controller", func() {
 		}).Should(BeTrue())
 	})
 
+	It("should recreate VDC whose job stays cancelled or terminated", func() {
+		By("creating AutoVirtualDC")
+		clock.SetTime(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		avdc := &nyamberv1beta1.AutoVirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-avdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.AutoVirtualDCSpec{
+				TimeoutDuration: "1h",
+			},
+		}
+		err := k8sClient.Create(ctx, avdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking VirtualDC is created")
+		vdc := &nyamberv1beta1.VirtualDC{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, vdc)
+		}).Should(Succeed())
+		previousVdcUid := vdc.UID
+
+		By("setting vdc's status to be terminated")
+		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
+			Type:   nyamberv1beta1.TypePodJobCompleted,
+			Status: metav1.ConditionFalse,
+			Reason: nyamberv1beta1.ReasonPodJobCompletedTerminated,
+		})
+		err = k8sClient.Status().Update(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the time when the job was found stopped is recorded")
+		Eventually(func(g Gomega) {
+			avdc := &nyamberv1beta1.AutoVirtualDC{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, avdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(avdc.Status.JobStoppedTime).NotTo(BeNil())
+		}).Should(Succeed())
+
+		By("checking vdc is not recreated in the grace period")
+		clock.Step(terminatedGracePeriod / 2)
+		Consistently(func(g Gomega) {
+			vdc := &nyamberv1beta1.VirtualDC{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, vdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(vdc.UID).To(Equal(previousVdcUid))
+		}).Should(Succeed())
+
+		By("checking vdc is recreated after the grace period")
+		clock.Step(terminatedGracePeriod)
+		Eventually(func(g Gomega) {
+			vdc = &nyamberv1beta1.VirtualDC{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, vdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(vdc.UID).NotTo(Equal(previousVdcUid))
+		}).Should(Succeed())
+		previousVdcUid = vdc.UID
+
+		By("setting vdc's status to be cancelled")
+		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
+			Type:   nyamberv1beta1.TypePodJobCompleted,
+			Status: metav1.ConditionFalse,
+			Reason: nyamberv1beta1.ReasonPodJobCompletedCancelled,
+		})
+		err = k8sClient.Status().Update(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the time when the job was found stopped is recorded")
+		Eventually(func(g Gomega) {
+			avdc := &nyamberv1beta1.AutoVirtualDC{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, avdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(avdc.Status.JobStoppedTime).NotTo(BeNil())
+		}).Should(Succeed())
+
+		By("checking vdc is not recreated before timeoutDuration elapses")
+		clock.Step(30 * time.Minute)
+		Consistently(func(g Gomega) {
+			vdc := &nyamberv1beta1.VirtualDC{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, vdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(vdc.UID).To(Equal(previousVdcUid))
+		}).Should(Succeed())
+
+		By("checking vdc is recreated after timeoutDuration elapses")
+		clock.Step(time.Hour)
+		Eventually(func(g Gomega) {
+			vdc = &nyamberv1beta1.VirtualDC{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-avdc", Namespace: testNamespace}, vdc)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(vdc.UID).NotTo(Equal(previousVdcUid))
+		}).Should(Succeed())
+	})
 	It("should operate VDC according to TimeoutDuration of AVDC when startSchedule/stopSchedule is not set", func() {
 		By("creating AutoVirtualDC")
 		clock.SetTime(time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC))
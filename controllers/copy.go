@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// copyName returns the name of the copy of a Secret or a ConfigMap of VirtualDC in the namespace of the runner pod.
+// The name contains a dot, which the name of VirtualDC does not contain because it is also the name of the Service,
+// so that the copies do not conflict with the objects named after VirtualDC.
+// If the name is too long for an object name, it is truncated and suffixed with the hash of the whole name.
+func copyName(vdcName, name string) string {
+	copied := vdcName + "." + name
+	if len(copied) <= validation.DNS1123SubdomainMaxLength {
+		return copied
+	}
+	sum := sha256.Sum256([]byte(copied))
+	suffix := "-" + hex.EncodeToString(sum[:])[:copyNameHashLength]
+	// Trim the dots and the hyphens at the end so that the truncated name is still a valid DNS subdomain.
+	return strings.TrimRight(copied[:validation.DNS1123SubdomainMaxLength-len(suffix)], ".-") + suffix
+}
+
+// copyNameHashLength is the length of the hash suffix of a truncated copy name.
+const copyNameHashLength = 10
+
+func copyLabels(vdc *nyamberv1beta1.VirtualDC) map[string]string {
+	return map[string]string{
+		constants.LabelKeyOwnerNamespace: vdc.Namespace,
+		constants.LabelKeyOwner:          vdc.Name,
+		constants.LabelKeyCopied:         "true",
+	}
+}
+
+func isCopyOf(obj client.Object, vdc *nyamberv1beta1.VirtualDC) bool {
+	labels := obj.GetLabels()
+	for k, v := range copyLabels(vdc) {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceRefs is the names of the Secrets and the ConfigMaps referenced in the spec of VirtualDC.
+// The value is true if all the references to the object are optional.
+type sourceRefs struct {
+	secrets    map[string]bool
+	configMaps map[string]bool
+}
+
+func addSourceRef(refs map[string]bool, name string, optional *bool) {
+	opt := optional != nil && *optional
+	if prev, ok := refs[name]; ok {
+		opt = opt && prev
+	}
+	refs[name] = opt
+}
+
+func referencedSources(spec *nyamberv1beta1.VirtualDCSpec) *sourceRefs {
+	refs := &sourceRefs{
+		secrets:    make(map[string]bool),
+		configMaps: make(map[string]bool),
+	}
+	for _, env := range spec.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+			addSourceRef(refs.secrets, ref.Name, ref.Optional)
+		}
+		if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+			addSourceRef(refs.configMaps, ref.Name, ref.Optional)
+		}
+	}
+	for _, envFrom := range spec.EnvFrom {
+		if ref := envFrom.SecretRef; ref != nil {
+			addSourceRef(refs.secrets, ref.Name, ref.Optional)
+		}
+		if ref := envFrom.ConfigMapRef; ref != nil {
+			addSourceRef(refs.configMaps, ref.Name, ref.Optional)
+		}
+	}
+	for _, mount := range spec.ConfigMapMounts {
+		addSourceRef(refs.configMaps, mount.Name, &mount.Optional)
+	}
+	return refs
+}
+
+// envWithCopies returns env and envFrom of VirtualDC whose references point to the copies.
+func envWithCopies(vdc *nyamberv1beta1.VirtualDC) ([]corev1.EnvVar, []corev1.EnvFromSource) {
+	var env []corev1.EnvVar
+	for _, e := range vdc.Spec.Env {
+		e := *e.DeepCopy()
+		if e.ValueFrom != nil {
+			if ref := e.ValueFrom.SecretKeyRef; ref != nil {
+				ref.Name = copyName(vdc.Name, ref.Name)
+			}
+			if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil {
+				ref.Name = copyName(vdc.Name, ref.Name)
+			}
+		}
+		env = append(env, e)
+	}
+
+	var envFrom []corev1.EnvFromSource
+	for _, e := range vdc.Spec.EnvFrom {
+		e := *e.DeepCopy()
+		if ref := e.SecretRef; ref != nil {
+			ref.Name = copyName(vdc.Name, ref.Name)
+		}
+		if ref := e.ConfigMapRef; ref != nil {
+			ref.Name = copyName(vdc.Name, ref.Name)
+		}
+		envFrom = append(envFrom, e)
+	}
+	return env, envFrom
+}
+
+// copySources copies the Secrets and the ConfigMaps referenced by VirtualDC to the namespace of the runner pod,
+// and deletes the copies which are no longer referenced.
+// A missing object is skipped if all the references to it are optional.
+// The copies are updated whenever VirtualDC is reconciled, but only the changes of ConfigMaps trigger the reconciliation.
+// Secrets are not watched because they are not cached, and they are only used in the environment variables,
+// which the runner container reads only when it starts.
+func (r *VirtualDCReconciler) copySources(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) error {
+	err := r.syncCopies(ctx, vdc)
+	if err != nil && !meta.IsStatusConditionTrue(vdc.Status.Conditions, nyamberv1beta1.TypePodCreated) {
+		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
+			Type:    nyamberv1beta1.TypePodCreated,
+			Status:  metav1.ConditionFalse,
+			Reason:  nyamberv1beta1.ReasonPodCreatedCopyFailed,
+			Message: err.Error(),
+		})
+	}
+	return err
+}
+
+func (r *VirtualDCReconciler) syncCopies(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) error {
+	refs := referencedSources(&vdc.Spec)
+
+	secrets := make(map[string]bool)
+	for name, optional := range refs.secrets {
+		src := &corev1.Secret{}
+		found, err := r.getSource(ctx, vdc, name, optional, src)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		if err := r.deleteSecretCopyOfOtherType(ctx, vdc, name, src.Type); err != nil {
+			return err
+		}
+		dst := &corev1.Secret{}
+		if err := r.copySource(ctx, vdc, name, dst, func() {
+			dst.Type = src.Type
+			dst.Data = src.Data
+		}); err != nil {
+			return err
+		}
+		secrets[dst.Name] = true
+	}
+
+	configMaps := make(map[string]bool)
+	for name, optional := range refs.configMaps {
+		src := &corev1.ConfigMap{}
+		found, err := r.getSource(ctx, vdc, name, optional, src)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		dst := &corev1.ConfigMap{}
+		if err := r.copySource(ctx, vdc, name, dst, func() {
+			dst.Data = src.Data
+			dst.BinaryData = src.BinaryData
+		}); err != nil {
+			return err
+		}
+		configMaps[dst.Name] = true
+	}
+
+	if _, err := r.deleteCopies(ctx, vdc, &corev1.SecretList{}, secrets); err != nil {
+		return err
+	}
+	if _, err := r.deleteCopies(ctx, vdc, &corev1.ConfigMapList{}, configMaps); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getSource gets the object named name in the namespace of VirtualDC.
+// It returns false without an error if the object is not found and optional is true.
+func (r *VirtualDCReconciler) getSource(ctx context.Context, vdc *nyamberv1beta1.VirtualDC, name string, optional bool, obj client.Object) (bool, error) {
+	err := r.Get(ctx, client.ObjectKey{Namespace: vdc.Namespace, Name: name}, obj)
+	if apierrors.IsNotFound(err) && optional {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s %s/%s: %w", kindOf(obj), vdc.Namespace, name, err)
+	}
+	return true, nil
+}
+
+// copySource creates or updates dst as the copy of the object named name.
+// copyData copies the data of the source to dst.
+func (r *VirtualDCReconciler) copySource(ctx context.Context, vdc *nyamberv1beta1.VirtualDC, name string, dst client.Object, copyData func()) error {
+	logger := log.FromContext(ctx)
+
+	dst.SetNamespace(r.PodNamespace)
+	dst.SetName(copyName(vdc.Name, name))
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, dst, func() error {
+		if ts := dst.GetCreationTimestamp(); !ts.IsZero() && !isCopyOf(dst, vdc) {
+			return fmt.Errorf("%s %s/%s already exists for another VirtualDC", kindOf(dst), dst.GetNamespace(), dst.GetName())
+		}
+		dst.SetLabels(mergeMap(dst.GetLabels(), copyLabels(vdc)))
+		copyData()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("copy reconciled", "kind", kindOf(dst), "source", name, "name", dst.GetName(), "operation", op)
+	}
+	return nil
+}
+
+// deleteSecretCopyOfOtherType deletes the copy of the Secret named name if its type is not secretType.
+// The type of a Secret is immutable, so the copy is created again when the type of the source is changed.
+func (r *VirtualDCReconciler) deleteSecretCopyOfOtherType(ctx context.Context, vdc *nyamberv1beta1.VirtualDC, name string, secretType corev1.SecretType) error {
+	dst := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: r.PodNamespace, Name: copyName(vdc.Name, name)}, dst)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get Secret %s/%s: %w", r.PodNamespace, dst.Name, err)
+	}
+	if !isCopyOf(dst, vdc) || dst.Type == secretType {
+		return nil
+	}
+	uid := dst.GetUID()
+	cond := metav1.Preconditions{
+		UID: &uid,
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, dst, &client.DeleteOptions{
+		Preconditions: &cond,
+	}))
+}
+
+// deleteCopies deletes the copies of VirtualDC of the kind of list except for those named in keep.
+// It returns true if there are copies to delete.
+func (r *VirtualDCReconciler) deleteCopies(ctx context.Context, vdc *nyamberv1beta1.VirtualDC, list client.ObjectList, keep map[string]bool) (bool, error) {
+	if err := r.List(ctx, list, client.InNamespace(r.PodNamespace), client.MatchingLabels(copyLabels(vdc))); err != nil {
+		return true, err
+	}
+	found := false
+	err := meta.EachListItem(list, func(o runtime.Object) error {
+		obj := o.(client.Object)
+		if keep[obj.GetName()] {
+			return nil
+		}
+		found = true
+		if !obj.GetDeletionTimestamp().IsZero() {
+			return nil
+		}
+		uid := obj.GetUID()
+		cond := metav1.Preconditions{
+			UID: &uid,
+		}
+		return client.IgnoreNotFound(r.Delete(ctx, obj, &client.DeleteOptions{
+			Preconditions: &cond,
+		}))
+	})
+	if err != nil {
+		return true, err
+	}
+	return found, nil
+}
+
+// deleteAllCopies deletes all the copies of VirtualDC.
+// It returns true if there are copies to delete.
+func (r *VirtualDCReconciler) deleteAllCopies(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) (bool, error) {
+	requeueSecrets, err := r.deleteCopies(ctx, vdc, &corev1.SecretList{}, nil)
+	if err != nil {
+		return true, err
+	}
+	requeueConfigMaps, err := r.deleteCopies(ctx, vdc, &corev1.ConfigMapList{}, nil)
+	if err != nil {
+		return true, err
+	}
+	return requeueSecrets || requeueConfigMaps, nil
+}
+
+// configMapVolumes returns the volumes of the copies of the ConfigMaps mounted by VirtualDC and their mounts.
+func configMapVolumes(vdc *nyamberv1beta1.VirtualDC) ([]corev1.Volume, []corev1.VolumeMount) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for i, mount := range vdc.Spec.ConfigMapMounts {
+		name := fmt.Sprintf("%s%d", constants.ConfigMapVolumeNamePrefix, i)
+		source := &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: copyName(vdc.Name, mount.Name)},
+			Items:                mount.Items,
+		}
+		if mount.Optional {
+			source.Optional = ptr.To(true)
+		}
+		volumes = append(volumes, corev1.Volume{
+			Name:         name,
+			VolumeSource: corev1.VolumeSource{ConfigMap: source},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      name,
+			MountPath: mount.MountPath,
+			ReadOnly:  true,
+		})
+	}
+	return volumes, mounts
+}
+
+// validateMountPaths returns an error if a mount path of the ConfigMaps mounted by VirtualDC overlaps with
+// one of mounts, which are the volume mounts of the runner container including those in the pod template.
+// The webhook cannot check this because the pod template may be changed after VirtualDC is created.
+func validateMountPaths(mounts, configMapMounts []corev1.VolumeMount) error {
+	var overlaps []string
+	for _, cm := range configMapMounts {
+		cmPath := path.Clean(cm.MountPath)
+		for _, m := range mounts {
+			mPath := path.Clean(m.MountPath)
+			if isSubPath(cmPath, mPath) || isSubPath(mPath, cmPath) {
+				overlaps = append(overlaps, fmt.Sprintf("%s overlaps with %s of volume %s", cm.MountPath, m.MountPath, m.Name))
+			}
+		}
+	}
+	if len(overlaps) > 0 {
+		return fmt.Errorf("invalid mount paths: %s", strings.Join(overlaps, ", "))
+	}
+	return nil
+}
+
+// isSubPath returns true if p is dir or a path under dir. Both paths must be clean.
+func isSubPath(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// referencedConfigMaps returns the names of the ConfigMaps referenced by VirtualDC for the field index.
+func referencedConfigMaps(obj client.Object) []string {
+	vdc := obj.(*nyamberv1beta1.VirtualDC)
+	var names []string
+	for name := range referencedSources(&vdc.Spec).configMaps {
+		names = append(names, name)
+	}
+	return names
+}
+
+func kindOf(obj client.Object) string {
+	switch obj.(type) {
+	case *corev1.Secret:
+		return "Secret"
+	case *corev1.ConfigMap:
+		return "ConfigMap"
+	}
+	return fmt.Sprintf("%T", obj)
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var _ = Describe("copyName", func() {
+	It("should truncate a long name with the hash", func() {
+		Expect(copyName("test-vdc", "github")).To(Equal("test-vdc.github"))
+
+		vdcName := strings.Repeat("a", 63)
+		name := copyName(vdcName, strings.Repeat("b", 180)+"-."+strings.Repeat("c", 100))
+		Expect(name).To(HaveLen(validation.DNS1123SubdomainMaxLength - 1))
+		Expect(validation.IsDNS1123Subdomain(name)).To(BeEmpty())
+		Expect(name).To(HavePrefix(vdcName + "." + strings.Repeat("b", 180) + "-"))
+
+		other := copyName(vdcName, strings.Repeat("b", 180)+"-."+strings.Repeat("d", 100))
+		Expect(other).NotTo(Equal(name))
+		Expect(other).To(HaveLen(validation.DNS1123SubdomainMaxLength - 1))
+	})
+})
@@ -15,6 +15,7 @@ import (
 	"github.com/cybozu-go/nyamber/pkg/entrypoint"
 	"github.com/cybozu-go/well"
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -23,12 +24,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const interval time.Duration = time.Second * 10 // for development.
+// maxFailedTests is the maximum number of the names of failed tests recorded in the status of VirtualDC.
+const maxFailedTests = 10
 
 type JobProcessManager interface {
 	Start(vdc *nyamberv1beta1.VirtualDC) error
 	Stop(vdc *nyamberv1beta1.VirtualDC) error
 	StopAll()
+
+	// Notify makes the process of the VirtualDC update the status immediately.
+	// It returns false if the process is not running.
+	Notify(vdc types.NamespacedName) bool
 }
 
 type jobProcessManager struct {
@@ -38,14 +44,18 @@ type jobProcessManager struct {
 	stopped      bool
 	processes    map[string]*jobWatchProcess
 	podNamespace string
+	pollInterval time.Duration
 }
 
-func NewJobProcessManager(log logr.Logger, k8sClient client.Client, podNamespace string) JobProcessManager {
+// NewJobProcessManager returns a JobProcessManager whose processes get the job states from the runner pods every pollInterval.
+// The runner pods notify the changes of the job states, so polling is a fallback for lost notifications.
+func NewJobProcessManager(log logr.Logger, k8sClient client.Client, podNamespace string, pollInterval time.Duration) JobProcessManager {
 	return &jobProcessManager{
 		log:          log.WithName("JobProcessManager"),
 		k8sClient:    k8sClient,
 		processes:    map[string]*jobWatchProcess{},
 		podNamespace: podNamespace,
+		pollInterval: pollInterval,
 	}
 }
 
@@ -64,6 +74,7 @@ func (j *jobProcessManager) Start(vdc *nyamberv1beta1.VirtualDC) error {
 			j.k8sClient,
 			vdc,
 			j.podNamespace,
+			j.pollInterval,
 		)
 		process.start()
 		j.processes[vdcNamespacedName] = process
@@ -85,6 +96,18 @@ func (j *jobProcessManager) Stop(vdc *nyamberv1beta1.VirtualDC) error {
 	return nil
 }
 
+func (j *jobProcessManager) Notify(vdc types.NamespacedName) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	process, ok := j.processes[vdc.String()]
+	if !ok {
+		return false
+	}
+	process.notify()
+	return true
+}
+
 func (j *jobProcessManager) StopAll() {
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -103,17 +126,23 @@ type jobWatchProcess struct {
 	vdcNamespace string
 	vdcName      string
 	podNamespace string
+	pollInterval time.Duration
 	cancel       func()
 	env          *well.Environment
+
+	// notified is notified when the runner pod notifies the changes of the job states.
+	notified chan struct{}
 }
 
-func newJobWatchProcess(log logr.Logger, k8sClient client.Client, vdc *nyamberv1beta1.VirtualDC, podNamespace string) *jobWatchProcess {
+func newJobWatchProcess(log logr.Logger, k8sClient client.Client, vdc *nyamberv1beta1.VirtualDC, podNamespace string, pollInterval time.Duration) *jobWatchProcess {
 	return &jobWatchProcess{
 		log:          log,
 		k8sClient:    k8sClient,
 		vdcNamespace: vdc.Namespace,
 		vdcName:      vdc.Name,
 		podNamespace: podNamespace,
+		pollInterval: pollInterval,
+		notified:     make(chan struct{}, 1),
 	}
 }
 
@@ -139,24 +168,35 @@ func (p *jobWatchProcess) stop() error {
 	return nil
 }
 
+// notify makes the process update the status without waiting for the next poll.
+// The notifications received while an update is pending are merged.
+func (p *jobWatchProcess) notify() {
+	select {
+	case p.notified <- struct{}{}:
+	default:
+	}
+}
+
 func (p *jobWatchProcess) run(ctx context.Context) {
-	ticker := time.NewTicker(interval)
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			for i := 0; i < 3; i++ {
-				retry, err := p.updateStatus(ctx)
-				if err != nil {
-					p.log.Error(err, "failed to update status")
-				}
-				if retry {
-					time.Sleep(time.Second * 1)
-					continue
-				}
-				break
+		case <-p.notified:
+		}
+		for i := 0; i < 3; i++ {
+			retry, err := p.updateStatus(ctx)
+			if err != nil {
+				p.log.Error(err, "failed to update status")
+			}
+			if retry {
+				time.Sleep(time.Second * 1)
+				continue
 			}
+			break
 		}
 	}
 }
@@ -166,17 +206,15 @@ func (p *jobWatchProcess) updateStatus(ctx context.Context) (bool, error) {
 	if err := p.k8sClient.Get(ctx, client.ObjectKey{Name: p.vdcName, Namespace: p.vdcNamespace}, beforeVdc); err != nil {
 		return false, err
 	}
-	jobStates, err := p.getJobStates()
+	jobStates, err := p.getJobStates(ctx)
 	if err != nil {
 		return false, err
 	}
 	vdc := beforeVdc.DeepCopy()
-	for _, job := range jobStates.Jobs {
+	if job, ok := currentJob(jobStates.Jobs); ok {
 		meta.SetStatusCondition(&vdc.Status.Conditions, getJobCondition(job))
-		if job.Status != entrypoint.JobStatusCompleted {
-			break
-		}
 	}
+	vdc.Status.TestSummary = getTestSummary(jobStates.Jobs)
 	if !equality.Semantic.DeepEqual(vdc.Status, beforeVdc.Status) {
 		p.log.Info("update status", "status", vdc.Status, "before", beforeVdc.Status)
 		if err := p.k8sClient.Status().Update(ctx, vdc); err != nil {
@@ -186,12 +224,33 @@ func (p *jobWatchProcess) updateStatus(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
-func (p *jobWatchProcess) getJobStates() (*entrypoint.StatusResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s.%s/%s", p.vdcName, p.podNamespace, constants.StatusEndPoint))
+// getToken returns the token to access the API of the runner pod.
+func (p *jobWatchProcess) getToken(ctx context.Context) (string, error) {
+	secret := &corev1.Secret{}
+	if err := p.k8sClient.Get(ctx, client.ObjectKey{Namespace: p.podNamespace, Name: p.vdcName}, secret); err != nil {
+		return "", err
+	}
+	return string(secret.Data[constants.TokenSecretKey]), nil
+}
+
+func (p *jobWatchProcess) getJobStates(ctx context.Context) (*entrypoint.StatusResponse, error) {
+	token, err := p.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s.%s/%s", p.vdcName, p.podNamespace, constants.StatusEndPoint), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get job states: %s", resp.Status)
+	}
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -203,6 +262,68 @@ func (p *jobWatchProcess) getJobStates() (*entrypoint.StatusResponse, error) {
 	return statusResp, nil
 }
 
+// currentJob returns the job which represents the progress of the jobs.
+// It is the first job which did not complete successfully, preferring failed jobs to running and pending ones
+// because jobs may run in parallel. If all jobs have completed, it is the last job.
+func currentJob(jobs []entrypoint.JobState) (entrypoint.JobState, bool) {
+	if len(jobs) == 0 {
+		return entrypoint.JobState{}, false
+	}
+	for _, job := range jobs {
+		switch job.Status {
+		case entrypoint.JobStatusFailed, entrypoint.JobStatusTimedOut, entrypoint.JobStatusCancelled, entrypoint.JobStatusInterrupted,
+			entrypoint.JobStatusTerminated:
+			return job, true
+		}
+	}
+	for _, job := range jobs {
+		if job.Status != entrypoint.JobStatusCompleted {
+			return job, true
+		}
+	}
+	return jobs[len(jobs)-1], true
+}
+
+// jobMessage returns the name of the job with the reason why it did not complete and the step reported by the job,
+// e.g. "neco_apps_bootstrap: exit code 1 (step: argocd sync)" or "neco_apps_bootstrap: argocd sync (40%)".
+func jobMessage(job entrypoint.JobState) string {
+	step := job.Step
+	if step != "" && job.Percent != nil {
+		step += fmt.Sprintf(" (%d%%)", *job.Percent)
+	}
+
+	switch {
+	case job.Message != "" && step != "":
+		return fmt.Sprintf("%s: %s (step: %s)", job.Name, job.Message, step)
+	case job.Message != "":
+		return job.Name + ": " + job.Message
+	case step != "":
+		return job.Name + ": " + step
+	}
+	return job.Name
+}
+
+// getTestSummary returns the summary of the test report of the last job which has one.
+func getTestSummary(jobs []entrypoint.JobState) *nyamberv1beta1.TestSummary {
+	for i := len(jobs) - 1; i >= 0; i-- {
+		report := jobs[i].TestReport
+		if report == nil {
+			continue
+		}
+		summary := &nyamberv1beta1.TestSummary{
+			Job:     jobs[i].Name,
+			Passed:  int32(report.Passed),
+			Failed:  int32(report.Failed),
+			Skipped: int32(report.Skipped),
+		}
+		if len(report.FailedTests) > 0 {
+			summary.FailedTests = report.FailedTests[:min(len(report.FailedTests), maxFailedTests)]
+		}
+		return summary
+	}
+	return nil
+}
+
 func getJobCondition(job entrypoint.JobState) metav1.Condition {
 	cond := metav1.Condition{
 		Type: nyamberv1beta1.TypePodJobCompleted,
@@ -211,11 +332,31 @@ func getJobCondition(job entrypoint.JobState) metav1.Condition {
 	case entrypoint.JobStatusFailed:
 		cond.Status = metav1.ConditionFalse
 		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedFailed
-		cond.Message = job.Name
+		cond.Message = jobMessage(job)
+	case entrypoint.JobStatusTimedOut:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedTimedOut
+		cond.Message = jobMessage(job)
+	case entrypoint.JobStatusSkipped:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedSkipped
+		cond.Message = jobMessage(job)
+	case entrypoint.JobStatusCancelled:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedCancelled
+		cond.Message = jobMessage(job)
+	case entrypoint.JobStatusInterrupted:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedInterrupted
+		cond.Message = jobMessage(job)
+	case entrypoint.JobStatusTerminated:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedTerminated
+		cond.Message = jobMessage(job)
 	case entrypoint.JobStatusRunning:
 		cond.Status = metav1.ConditionFalse
 		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedRunning
-		cond.Message = job.Name
+		cond.Message = jobMessage(job)
 	case entrypoint.JobStatusPending:
 		cond.Status = metav1.ConditionFalse
 		cond.Reason = nyamberv1beta1.ReasonPodJobCompletedPending
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NotifyServer receives the notifications of the changes of the job states from the runner pods,
+// and makes JobProcessManager update the status of VirtualDC without waiting for the next poll.
+// A runner pod sends a notification to /notify/<namespace>/<name> of its VirtualDC with the token of the VirtualDC.
+type NotifyServer struct {
+	Addr              string
+	Client            client.Client
+	PodNamespace      string
+	JobProcessManager JobProcessManager
+	Log               logr.Logger
+}
+
+// Start runs the server until ctx is done. This implements manager.Runnable.
+func (s *NotifyServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+constants.NotifyEndPoint+"/", s.notifyHandler)
+	serv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.Log.Info("notify server start", "addr", s.Addr)
+		errCh <- serv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := serv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns true because only the leader has the processes to update the status.
+// The other replicas do not listen, and the runner pods retry the notifications refused by them.
+func (s *NotifyServer) NeedLeaderElection() bool {
+	return true
+}
+
+func (s *NotifyServer) notifyHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"+constants.NotifyEndPoint+"/"), "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		http.Error(w, "path must be /notify/<namespace>/<name>", http.StatusNotFound)
+		return
+	}
+	vdc := types.NamespacedName{Namespace: namespace, Name: name}
+
+	token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	valid, err := s.authenticate(req.Context(), vdc, token)
+	if err != nil {
+		s.Log.Error(err, "failed to get the token", "vdc", vdc.String())
+		http.Error(w, "failed to get the token", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.JobProcessManager.Notify(vdc) {
+		http.Error(w, fmt.Sprintf("VirtualDC %s is not watched", vdc), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authenticate returns true if token is the token of the runner pod of the VirtualDC.
+func (s *NotifyServer) authenticate(ctx context.Context, vdc types.NamespacedName, token string) (bool, error) {
+	secret := &corev1.Secret{}
+	err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.PodNamespace, Name: vdc.Name}, secret)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if secret.Labels[constants.LabelKeyOwnerNamespace] != vdc.Namespace {
+		return false, nil
+	}
+	expected := secret.Data[constants.TokenSecretKey]
+	return len(expected) != 0 && subtle.ConstantTimeCompare([]byte(token), expected) == 1, nil
+}
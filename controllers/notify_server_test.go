@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const testNotifyAddr = "localhost:18082"
+
+var _ = Describe("NotifyServer", func() {
+	ctx := context.Background()
+	var stopFunc func()
+	mock := &mockJobProcessManager{
+		mu:        sync.Mutex{},
+		processes: make(map[string]struct{}),
+	}
+
+	BeforeEach(func() {
+		server := &NotifyServer{
+			Addr:              testNotifyAddr,
+			Client:            k8sClient,
+			PodNamespace:      testPodNamespace,
+			JobProcessManager: mock,
+			Log:               logf.Log.WithName("NotifyServer"),
+		}
+		cctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			defer GinkgoRecover()
+			Expect(server.Start(cctx)).To(Succeed())
+		}()
+		Eventually(func() error {
+			_, err := http.Get("http://" + testNotifyAddr)
+			return err
+		}).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		err := k8sClient.DeleteAllOf(ctx, &corev1.Secret{}, client.InNamespace(testPodNamespace))
+		Expect(err).NotTo(HaveOccurred())
+		mock.StopAll()
+		mock.notified = nil
+		stopFunc()
+	})
+
+	It("should notify the process of VirtualDC with the token", func() {
+		By("creating the token and the process of a VirtualDC")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testPodNamespace,
+				Labels: map[string]string{
+					constants.LabelKeyOwnerNamespace: testNamespace,
+					constants.LabelKeyOwner:          "test-vdc",
+				},
+			},
+			Data: map[string][]byte{constants.TokenSecretKey: []byte("secret")},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		vdc := &nyamberv1beta1.VirtualDC{ObjectMeta: metav1.ObjectMeta{Name: "test-vdc", Namespace: testNamespace}}
+		Expect(mock.Start(vdc)).To(Succeed())
+
+		By("rejecting notifications without the valid token")
+		Expect(postNotification(testNamespace, "test-vdc", "")).To(Equal(http.StatusUnauthorized))
+		Expect(postNotification(testNamespace, "test-vdc", "wrong")).To(Equal(http.StatusUnauthorized))
+		Expect(postNotification("other-ns", "test-vdc", "secret")).To(Equal(http.StatusUnauthorized))
+		Expect(postNotification(testNamespace, "unknown", "secret")).To(Equal(http.StatusUnauthorized))
+		Expect(mock.notified).To(BeEmpty())
+
+		By("notifying the process")
+		Expect(postNotification(testNamespace, "test-vdc", "secret")).To(Equal(http.StatusAccepted))
+		Expect(mock.notified).To(Equal([]string{testNamespace + "/test-vdc"}))
+
+		By("rejecting notifications for VirtualDC without the process")
+		Expect(mock.Stop(vdc)).To(Succeed())
+		Expect(postNotification(testNamespace, "test-vdc", "secret")).To(Equal(http.StatusNotFound))
+	})
+
+	It("should accept the notification retried after a miss", func() {
+		By("creating the token of a VirtualDC without the process")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testPodNamespace,
+				Labels: map[string]string{
+					constants.LabelKeyOwnerNamespace: testNamespace,
+					constants.LabelKeyOwner:          "test-vdc",
+				},
+			},
+			Data: map[string][]byte{constants.TokenSecretKey: []byte("secret")},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		Expect(postNotification(testNamespace, "test-vdc", "secret")).To(Equal(http.StatusNotFound))
+		Expect(mock.notified).To(BeEmpty())
+
+		By("retrying the notification after the process starts")
+		vdc := &nyamberv1beta1.VirtualDC{ObjectMeta: metav1.ObjectMeta{Name: "test-vdc", Namespace: testNamespace}}
+		Expect(mock.Start(vdc)).To(Succeed())
+		Expect(postNotification(testNamespace, "test-vdc", "secret")).To(Equal(http.StatusAccepted))
+		Expect(mock.notified).To(Equal([]string{testNamespace + "/test-vdc"}))
+	})
+})
+
+func postNotification(namespace, name, token string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/%s/%s/%s", testNotifyAddr, constants.NotifyEndPoint, namespace, name), nil)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -18,12 +18,20 @@ package controllers
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"path"
 	"strings"
 	"time"
 
 	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
 	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/cybozu-go/nyamber/pkg/entrypoint"
+	"github.com/cybozu-go/nyamber/pkg/resources"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -41,12 +49,21 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+// configMapIndexKey is the key of the field index of VirtualDC by the names of the ConfigMaps referenced by it.
+const configMapIndexKey = ".spec.configMaps"
+
 // VirtualDCReconciler reconciles a VirtualDC object
 type VirtualDCReconciler struct {
 	client.Client
 	Scheme            *runtime.Scheme
 	PodNamespace      string
 	JobProcessManager JobProcessManager
+
+	// NotifyURL is the base URL of NotifyServer. If this is set, the runner pods notify the changes of the job states.
+	NotifyURL string
+
+	// MaxResources is the maximum of the requests and the limits of the runner container.
+	MaxResources corev1.ResourceList
 }
 
 //+kubebuilder:rbac:groups=nyamber.cybozu.io,resources=virtualdcs,verbs=get;list;watch;create;update;patch;delete
@@ -59,6 +76,8 @@ type VirtualDCReconciler struct {
 
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 // TODO(user): Modify the Reconcile function to compare the state specified by
@@ -103,6 +122,18 @@ func (r *VirtualDCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}(*vdc.Status.DeepCopy())
 
+	if err := r.createTokenSecrets(ctx, vdc); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.copySources(ctx, vdc); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.createJobsFile(ctx, vdc); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	if !meta.IsStatusConditionTrue(vdc.Status.Conditions, nyamberv1beta1.TypePodCreated) {
 		if err := r.createPod(ctx, vdc); err != nil {
 			return ctrl.Result{}, err
@@ -142,6 +173,20 @@ func (r *VirtualDCReconciler) getPodTemplate(ctx context.Context) (*corev1.Pod,
 	return pod, nil
 }
 
+// entrypointProbe returns a probe to check the endpoint of the entrypoint.
+func entrypointProbe(endpoint string) *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/" + endpoint,
+				Port: intstr.FromInt(constants.ListenPort),
+			},
+		},
+		PeriodSeconds:    10,
+		FailureThreshold: 3,
+	}
+}
+
 func (r *VirtualDCReconciler) createPod(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) error {
 	logger := log.FromContext(ctx)
 
@@ -166,7 +211,21 @@ func (r *VirtualDCReconciler) createPod(ctx context.Context, vdc *nyamberv1beta1
 		constants.LabelKeyOwner:          vdc.Name,
 	}))
 
+	setScheduling(&pod.Spec, &vdc.Spec)
+
 	container := &pod.Spec.Containers[0]
+	container.Resources = resources.Merge(container.Resources, vdc.Spec.Resources)
+	if violations := resources.Validate(container.Resources, r.MaxResources); len(violations) > 0 {
+		err := fmt.Errorf("invalid resources: %s", strings.Join(violations, ", "))
+		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
+			Type:    nyamberv1beta1.TypePodCreated,
+			Status:  metav1.ConditionFalse,
+			Reason:  nyamberv1beta1.ReasonPodCreatedInvalidResources,
+			Message: err.Error(),
+		})
+		return err
+	}
+
 	if vdc.Spec.NecoBranch == "" {
 		vdc.Spec.NecoBranch = "main"
 	}
@@ -178,25 +237,103 @@ func (r *VirtualDCReconciler) createPod(ctx context.Context, vdc *nyamberv1beta1
 		Value: vdc.Spec.NecoBranch,
 	})
 
-	container.Args = []string{"neco_bootstrap:/scripts/neco-bootstrap"}
-
 	if !vdc.Spec.SkipNecoApps {
 		container.Env = append(container.Env, corev1.EnvVar{
 			Name:  "NECO_APPS_BRANCH",
 			Value: vdc.Spec.NecoAppsBranch,
 		})
+	}
+
+	env, envFrom := envWithCopies(vdc)
+	container.Env = append(container.Env, env...)
+	container.EnvFrom = append(container.EnvFrom, envFrom...)
 
-		container.Args = append(
-			container.Args,
-			"neco_apps_bootstrap:/scripts/neco-apps-bootstrap",
-		)
+	jobs := runnerJobs(&vdc.Spec)
+	var args []string
+	if len(vdc.Spec.Jobs) == 0 {
+		for _, job := range jobs {
+			args = append(args, job.Name+":"+strings.Join(job.Command, " "))
+		}
 	}
 
-	if len(vdc.Spec.Command) != 0 {
-		container.Args = append(
-			container.Args,
-			"user_defined_command:"+strings.Join(vdc.Spec.Command, " "),
-		)
+	var options []string
+	for _, job := range jobs {
+		name := job.Name
+		if timeout, ok := vdc.Spec.JobTimeouts[name]; ok {
+			options = append(options, fmt.Sprintf("--job-timeout=%s=%s", name, timeout.Duration))
+		}
+		if retries, ok := vdc.Spec.JobRetries[name]; ok {
+			options = append(options, fmt.Sprintf("--job-retries=%s=%d", name, retries))
+		}
+		if path, ok := vdc.Spec.TestReports[name]; ok {
+			options = append(options, fmt.Sprintf("--test-report=%s=%s", name, path))
+		}
+	}
+	if vdc.Spec.ResumePolicy != "" {
+		options = append(options, "--resume-policy="+vdc.Spec.ResumePolicy)
+	}
+	if vdc.Spec.ReadyJob != "" {
+		options = append(options, "--ready-job="+vdc.Spec.ReadyJob)
+	}
+	if vdc.Spec.Terminal {
+		options = append(options, "--terminal")
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: constants.TokenVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: vdc.Name},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      constants.TokenVolumeName,
+		MountPath: constants.TokenMountPath,
+		ReadOnly:  true,
+	})
+	if len(vdc.Spec.Jobs) != 0 {
+		// The arguments of the commands in spec.jobs may contain spaces, so they are given by the jobs file.
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: constants.JobsVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: vdc.Name},
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      constants.JobsVolumeName,
+			MountPath: constants.JobsMountPath,
+			ReadOnly:  true,
+		})
+		options = append(options, "--jobs-file="+path.Join(constants.JobsMountPath, constants.JobsFileKey))
+	}
+	volumes, mounts := configMapVolumes(vdc)
+	if err := validateMountPaths(container.VolumeMounts, mounts); err != nil {
+		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
+			Type:    nyamberv1beta1.TypePodCreated,
+			Status:  metav1.ConditionFalse,
+			Reason:  nyamberv1beta1.ReasonPodCreatedInvalidMounts,
+			Message: err.Error(),
+		})
+		return err
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
+	container.VolumeMounts = append(container.VolumeMounts, mounts...)
+	options = append(options, "--token-file="+path.Join(constants.TokenMountPath, constants.TokenSecretKey))
+	if r.NotifyURL != "" {
+		notifyURL, err := url.JoinPath(r.NotifyURL, constants.NotifyEndPoint, vdc.Namespace, vdc.Name)
+		if err != nil {
+			return err
+		}
+		options = append(options, "--notify-url="+notifyURL)
+	}
+	container.Args = append(options, args...)
+
+	if container.LivenessProbe == nil {
+		container.LivenessProbe = entrypointProbe(constants.HealthzEndPoint)
+	}
+	if container.ReadinessProbe == nil {
+		container.ReadinessProbe = entrypointProbe(constants.ReadyzEndPoint)
 	}
 
 	if err := r.Create(ctx, pod); err != nil {
@@ -263,6 +400,8 @@ func (r *VirtualDCReconciler) createService(ctx context.Context, vdc *nyamberv1b
 					TargetPort: intstr.FromInt(constants.ListenPort),
 				},
 			},
+			// The controller watches the jobs through the service before the runner pod becomes ready.
+			PublishNotReadyAddresses: true,
 		},
 	}
 	err := r.Create(ctx, svc)
@@ -306,6 +445,131 @@ func (r *VirtualDCReconciler) createService(ctx context.Context, vdc *nyamberv1b
 	return nil
 }
 
+// createTokenSecrets creates the Secret which has the token to access the API of the runner pod,
+// and copies it to the namespace of VirtualDC so that the owner of VirtualDC can read it.
+func (r *VirtualDCReconciler) createTokenSecrets(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) error {
+	logger := log.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: r.PodNamespace, Name: vdc.Name}, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		token, err := generateToken()
+		if err != nil {
+			return err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      vdc.Name,
+				Namespace: r.PodNamespace,
+				Labels: map[string]string{
+					constants.LabelKeyOwnerNamespace: vdc.Namespace,
+					constants.LabelKeyOwner:          vdc.Name,
+				},
+			},
+			Data: map[string][]byte{
+				constants.TokenSecretKey: []byte(token),
+			},
+		}
+		if err := r.Create(ctx, secret); err != nil {
+			return err
+		}
+		logger.Info("Secret created")
+	case err != nil:
+		return err
+	case secret.Labels[constants.LabelKeyOwnerNamespace] != vdc.Namespace:
+		return fmt.Errorf("secret %s/%s already exists for another namespace", r.PodNamespace, vdc.Name)
+	}
+
+	userSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vdc.Name + constants.TokenSecretSuffix,
+			Namespace: vdc.Namespace,
+		},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, userSecret, func() error {
+		if !userSecret.CreationTimestamp.IsZero() && !metav1.IsControlledBy(userSecret, vdc) {
+			return fmt.Errorf("secret %s/%s is not owned by the VirtualDC", userSecret.Namespace, userSecret.Name)
+		}
+		userSecret.Data = map[string][]byte{
+			constants.TokenSecretKey: secret.Data[constants.TokenSecretKey],
+		}
+		return ctrl.SetControllerReference(vdc, userSecret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("token Secret reconciled", "name", userSecret.Name, "operation", op)
+	}
+	return nil
+}
+
+// runnerJobs returns the jobs run in the runner pod.
+func runnerJobs(spec *nyamberv1beta1.VirtualDCSpec) []entrypoint.JobSpec {
+	jobs := []entrypoint.JobSpec{{Name: constants.JobNameNecoBootstrap, Command: []string{"/scripts/neco-bootstrap"}}}
+	if !spec.SkipNecoApps {
+		jobs = append(jobs, entrypoint.JobSpec{Name: constants.JobNameNecoAppsBootstrap, Command: []string{"/scripts/neco-apps-bootstrap"}})
+	}
+	if len(spec.Command) != 0 {
+		jobs = append(jobs, entrypoint.JobSpec{Name: constants.JobNameUserDefinedCommand, Command: spec.Command})
+	}
+	for _, job := range spec.Jobs {
+		jobs = append(jobs, entrypoint.JobSpec{Name: job.Name, Command: job.Command})
+	}
+	return jobs
+}
+
+// createJobsFile creates the ConfigMap which has the jobs file for the runner pod if VirtualDC has spec.jobs.
+// DependsOn of the jobs is null, so that each job depends on the previous one.
+func (r *VirtualDCReconciler) createJobsFile(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) error {
+	logger := log.FromContext(ctx)
+
+	if len(vdc.Spec.Jobs) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(entrypoint.JobsFile{Jobs: runnerJobs(&vdc.Spec)})
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vdc.Name,
+			Namespace: r.PodNamespace,
+		},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		if !cm.CreationTimestamp.IsZero() && cm.Labels[constants.LabelKeyOwnerNamespace] != vdc.Namespace {
+			return fmt.Errorf("configmap %s/%s already exists for another namespace", cm.Namespace, cm.Name)
+		}
+		cm.Labels = mergeMap(cm.Labels, map[string]string{
+			constants.LabelKeyOwnerNamespace: vdc.Namespace,
+			constants.LabelKeyOwner:          vdc.Name,
+		})
+		cm.Data = map[string]string{
+			constants.JobsFileKey: string(data),
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("jobs file reconciled", "operation", op)
+	}
+	return nil
+}
+
+// generateToken returns a random token to access the runner pod.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func (r *VirtualDCReconciler) updateStatus(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) error {
 	pod := &corev1.Pod{}
 	if err := r.Get(ctx, client.ObjectKey{Namespace: r.PodNamespace, Name: vdc.Name}, pod); err != nil {
@@ -332,9 +596,10 @@ func (r *VirtualDCReconciler) updateStatus(ctx context.Context, vdc *nyamberv1be
 	}
 	if !isStatusConditionTrue(pod, corev1.PodScheduled) {
 		meta.SetStatusCondition(&vdc.Status.Conditions, metav1.Condition{
-			Type:   nyamberv1beta1.TypePodAvailable,
-			Status: metav1.ConditionFalse,
-			Reason: nyamberv1beta1.ReasonPodAvailableNotScheduled,
+			Type:    nyamberv1beta1.TypePodAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  nyamberv1beta1.ReasonPodAvailableNotScheduled,
+			Message: notScheduledMessage(pod),
 		})
 		return nil
 	}
@@ -374,8 +639,23 @@ func (r *VirtualDCReconciler) finalize(ctx context.Context, vdc *nyamberv1beta1.
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	if requeueService || requeuePod {
-		logger.Info("requeue has occurred", "service", requeueService, "pod", requeuePod)
+
+	requeueSecret, err := r.deleteSecret(ctx, vdc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	requeueJobsFile, err := r.deleteJobsFile(ctx, vdc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	requeueCopies, err := r.deleteAllCopies(ctx, vdc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if requeueService || requeuePod || requeueSecret || requeueJobsFile || requeueCopies {
+		logger.Info("requeue has occurred", "service", requeueService, "pod", requeuePod, "secret", requeueSecret, "jobsFile", requeueJobsFile, "copies", requeueCopies)
 		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 
@@ -444,6 +724,60 @@ func (r *VirtualDCReconciler) deleteService(ctx context.Context, vdc *nyamberv1b
 	return true, nil
 }
 
+func (r *VirtualDCReconciler) deleteSecret(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) (bool, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: r.PodNamespace, Name: vdc.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return true, err
+	}
+	ownerNs, ok := secret.Labels[constants.LabelKeyOwnerNamespace]
+	if !ok || ownerNs != vdc.Namespace {
+		return false, nil
+	}
+	if !secret.ObjectMeta.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+	uid := secret.GetUID()
+	cond := metav1.Preconditions{
+		UID: &uid,
+	}
+	if err := r.Delete(ctx, secret, &client.DeleteOptions{
+		Preconditions: &cond,
+	}); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func (r *VirtualDCReconciler) deleteJobsFile(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) (bool, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: r.PodNamespace, Name: vdc.Name}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return true, err
+	}
+	ownerNs, ok := cm.Labels[constants.LabelKeyOwnerNamespace]
+	if !ok || ownerNs != vdc.Namespace {
+		return false, nil
+	}
+	if !cm.ObjectMeta.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+	uid := cm.GetUID()
+	cond := metav1.Preconditions{
+		UID: &uid,
+	}
+	if err := r.Delete(ctx, cm, &client.DeleteOptions{
+		Preconditions: &cond,
+	}); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *VirtualDCReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	vdcHandler := func(c context.Context, o client.Object) []reconcile.Request {
@@ -454,10 +788,34 @@ func (r *VirtualDCReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: owner, Name: o.GetName()}}}
 	}
 
+	// configMapHandler reconciles VirtualDC when the ConfigMaps referenced by it or those created for it,
+	// i.e. the copies and the jobs file, are changed, so that the changes propagate to the runner pod.
+	configMapHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		labels := o.GetLabels()
+		if labels[constants.LabelKeyOwnerNamespace] != "" && labels[constants.LabelKeyOwner] != "" {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: labels[constants.LabelKeyOwnerNamespace], Name: labels[constants.LabelKeyOwner]}}}
+		}
+		vdcs := &nyamberv1beta1.VirtualDCList{}
+		if err := mgr.GetClient().List(ctx, vdcs, client.InNamespace(o.GetNamespace()), client.MatchingFields{configMapIndexKey: o.GetName()}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list VirtualDCs referencing the ConfigMap", "namespace", o.GetNamespace(), "name", o.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(vdcs.Items))
+		for _, vdc := range vdcs.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: vdc.Namespace, Name: vdc.Name}})
+		}
+		return requests
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &nyamberv1beta1.VirtualDC{}, configMapIndexKey, referencedConfigMaps); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&nyamberv1beta1.VirtualDC{}).
 		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(vdcHandler)).
 		Watches(&corev1.Service{}, handler.EnqueueRequestsFromMapFunc(vdcHandler)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(configMapHandler)).
 		Complete(r)
 }
 
@@ -473,6 +831,24 @@ func isStatusConditionTrue(pod *corev1.Pod, condition corev1.PodConditionType) b
 	return false
 }
 
+// notScheduledMessage returns why the scheduler has not scheduled the pod,
+// e.g. "Unschedulable: 0/3 nodes are available: 3 Insufficient memory."
+func notScheduledMessage(pod *corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type != corev1.PodScheduled {
+			continue
+		}
+		switch {
+		case cond.Reason != "" && cond.Message != "":
+			return cond.Reason + ": " + cond.Message
+		case cond.Message != "":
+			return cond.Message
+		}
+		return cond.Reason
+	}
+	return ""
+}
+
 func mergeMap(m1, m2 map[string]string) map[string]string {
 	m := make(map[string]string)
 	for k, v := range m1 {
@@ -486,3 +862,25 @@ func mergeMap(m1, m2 map[string]string) map[string]string {
 	}
 	return m
 }
+
+// setScheduling merges the scheduling constraints in VirtualDC into the pod spec from the pod template.
+func setScheduling(podSpec *corev1.PodSpec, spec *nyamberv1beta1.VirtualDCSpec) {
+	podSpec.NodeSelector = mergeMap(podSpec.NodeSelector, spec.NodeSelector)
+	podSpec.Tolerations = append(podSpec.Tolerations, spec.Tolerations...)
+
+	if spec.Affinity == nil {
+		return
+	}
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity != nil {
+		podSpec.Affinity.NodeAffinity = spec.Affinity.NodeAffinity.DeepCopy()
+	}
+	if spec.Affinity.PodAffinity != nil {
+		podSpec.Affinity.PodAffinity = spec.Affinity.PodAffinity.DeepCopy()
+	}
+	if spec.Affinity.PodAntiAffinity != nil {
+		podSpec.Affinity.PodAntiAffinity = spec.Affinity.PodAntiAffinity.DeepCopy()
+	}
+}
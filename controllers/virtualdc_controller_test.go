@@ -9,12 +9,14 @@ import (
 
 	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
 	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/cybozu-go/nyamber/pkg/entrypoint"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -29,6 +31,7 @@ type mockJobProcessManager struct {
 	mu        sync.Mutex
 	stopped   bool
 	processes map[string]struct{}
+	notified  []string
 }
 
 func (m *mockJobProcessManager) Start(vdc *nyamberv1beta1.VirtualDC) error {
@@ -54,6 +57,17 @@ func (m *mockJobProcessManager) Stop(vdc *nyamberv1beta1.VirtualDC) error {
 	return nil
 }
 
+func (m *mockJobProcessManager) Notify(vdc types.NamespacedName) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.processes[vdc.String()]; !ok {
+		return false
+	}
+	m.notified = append(m.notified, vdc.String())
+	return true
+}
+
 func (m *mockJobProcessManager) StopAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -78,6 +92,11 @@ var _ = Describe("VirtualDC controller", func() {
 			Controller: config.Controller{
 				SkipNameValidation: ptr.To(true),
 			},
+			Client: client.Options{
+				Cache: &client.CacheOptions{
+					DisableFor: []client.Object{&corev1.Secret{}},
+				},
+			},
 		})
 		Expect(err).NotTo(HaveOccurred())
 
@@ -87,6 +106,9 @@ var _ = Describe("VirtualDC controller", func() {
 			Scheme:            mgr.GetScheme(),
 			PodNamespace:      testPodNamespace,
 			JobProcessManager: &mock,
+			MaxResources: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("32Gi"),
+			},
 		}
 		err = nr.SetupWithManager(mgr)
 		Expect(err).NotTo(HaveOccurred())
@@ -138,6 +160,9 @@ spec:
 		}
 		err = k8sClient.DeleteAllOf(ctx, &nyamberv1beta1.VirtualDC{}, client.InNamespace(testNamespace))
 		Expect(err).NotTo(HaveOccurred())
+		// envtest does not run the garbage collector, so delete the Secrets owned by VirtualDC.
+		err = k8sClient.DeleteAllOf(ctx, &corev1.Secret{}, client.InNamespace(testNamespace))
+		Expect(err).NotTo(HaveOccurred())
 		Eventually(func() error {
 			vdcs := &nyamberv1beta1.VirtualDCList{}
 			if err := k8sClient.List(ctx, vdcs, client.InNamespace(testNamespace)); err != nil {
@@ -211,6 +236,22 @@ spec:
 				"neco_bootstrap:/scripts/neco-bootstrap",
 				"neco_apps_bootstrap:/scripts/neco-apps-bootstrap",
 			}),
+			"LivenessProbe": PointTo(MatchFields(IgnoreExtras, Fields{
+				"ProbeHandler": MatchFields(IgnoreExtras, Fields{
+					"HTTPGet": PointTo(MatchFields(IgnoreExtras, Fields{
+						"Path": Equal("/healthz"),
+						"Port": Equal(intstr.FromInt(constants.ListenPort)),
+					})),
+				}),
+			})),
+			"ReadinessProbe": PointTo(MatchFields(IgnoreExtras, Fields{
+				"ProbeHandler": MatchFields(IgnoreExtras, Fields{
+					"HTTPGet": PointTo(MatchFields(IgnoreExtras, Fields{
+						"Path": Equal("/readyz"),
+						"Port": Equal(intstr.FromInt(constants.ListenPort)),
+					})),
+				}),
+			})),
 		}))
 
 		By("checking to create svc")
@@ -238,6 +279,7 @@ spec:
 					TargetPort: intstr.FromInt(constants.ListenPort),
 				},
 			}),
+			"PublishNotReadyAddresses": BeTrue(),
 		}))
 
 		By("checking to call JobProcessManager.Start")
@@ -376,7 +418,7 @@ spec:
 					Value: "main",
 				},
 			}),
-			"Args": Equal([]string{"neco_bootstrap:/scripts/neco-bootstrap"}),
+			"Args": Equal([]string{"--token-file=/var/run/nyamber/token", "neco_bootstrap:/scripts/neco-bootstrap"}),
 		}))
 	})
 
@@ -402,12 +444,719 @@ spec:
 
 		By("checking to set command of pod")
 		Expect(pod.Spec.Containers[0].Args).To(Equal([]string{
+			"--token-file=/var/run/nyamber/token",
 			"neco_bootstrap:/scripts/neco-bootstrap",
 			"neco_apps_bootstrap:/scripts/neco-apps-bootstrap",
 			"user_defined_command:test command",
 		}))
 	})
 
+	It("should create a pod with the jobs file when the user specifies jobs", func() {
+		By("creating a VirtualDC resource")
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				SkipNecoApps: true,
+				Jobs: []nyamberv1beta1.Job{
+					{Name: "deploy", Command: []string{"/scripts/deploy"}},
+					{Name: "run_tests", Command: []string{"sh", "-c", "make test"}},
+				},
+				JobTimeouts: map[string]metav1.Duration{"run_tests": {Duration: time.Hour}},
+			},
+		}
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking to create pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+		Expect(pod.Spec.Containers[0].Args).To(Equal([]string{
+			"--job-timeout=run_tests=1h0m0s",
+			"--jobs-file=/etc/nyamber/jobs.json",
+			"--token-file=/var/run/nyamber/token",
+		}))
+		Expect(pod.Spec.Volumes).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name": Equal(constants.JobsVolumeName),
+			"VolumeSource": MatchFields(IgnoreExtras, Fields{
+				"ConfigMap": PointTo(MatchFields(IgnoreExtras, Fields{
+					"LocalObjectReference": Equal(corev1.LocalObjectReference{Name: "test-vdc"}),
+				})),
+			}),
+		})))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      constants.JobsVolumeName,
+			MountPath: constants.JobsMountPath,
+			ReadOnly:  true,
+		}))
+
+		By("checking the jobs file")
+		cm := &corev1.ConfigMap{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, cm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cm.Labels).To(MatchAllKeys(Keys{
+			constants.LabelKeyOwnerNamespace: Equal(testNamespace),
+			constants.LabelKeyOwner:          Equal("test-vdc"),
+		}))
+		jobs, err := entrypoint.ParseJobsFile([]byte(cm.Data[constants.JobsFileKey]))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs).To(Equal([]entrypoint.Job{
+			{Name: "neco_bootstrap", Command: "/scripts/neco-bootstrap", Args: []string{}},
+			{Name: "deploy", Command: "/scripts/deploy", Args: []string{}},
+			{Name: "run_tests", Command: "sh", Args: []string{"-c", "make test"}},
+		}))
+
+		By("recreating the jobs file when it is deleted")
+		err = k8sClient.Delete(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, cm)
+		}).Should(Succeed())
+
+		By("deleting the VirtualDC")
+		err = k8sClient.Delete(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, cm)
+			return apierrors.IsNotFound(err)
+		}).Should(BeTrue())
+	})
+
+	It("should create a pod with job options set by VirtualDC spec", func() {
+		By("creating a VirtualDC resource")
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				SkipNecoApps: true,
+				Command:      []string{"test", "command"},
+				JobTimeouts: map[string]metav1.Duration{
+					"neco_bootstrap":       {Duration: 2 * time.Hour},
+					"user_defined_command": {Duration: 30 * time.Minute},
+				},
+				JobRetries: map[string]int32{
+					"neco_bootstrap": 2,
+				},
+				TestReports: map[string]string{
+					"user_defined_command": "/tmp/dctest.json",
+				},
+				ResumePolicy: "Interrupt",
+				ReadyJob:     "neco_bootstrap",
+			},
+		}
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking to create pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+
+		By("checking to set the options of the jobs")
+		Expect(pod.Spec.Containers[0].Args).To(Equal([]string{
+			"--job-timeout=neco_bootstrap=2h0m0s",
+			"--job-retries=neco_bootstrap=2",
+			"--job-timeout=user_defined_command=30m0s",
+			"--test-report=user_defined_command=/tmp/dctest.json",
+			"--resume-policy=Interrupt",
+			"--ready-job=neco_bootstrap",
+			"--token-file=/var/run/nyamber/token",
+			"neco_bootstrap:/scripts/neco-bootstrap",
+			"user_defined_command:test command",
+		}))
+	})
+
+	It("should create token secrets and mount the token in the pod", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Terminal: true,
+			},
+		}
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking to create the secret in the pod namespace")
+		secret := &corev1.Secret{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, secret)
+		}).Should(Succeed())
+		Expect(secret.Labels).To(MatchAllKeys(Keys{
+			constants.LabelKeyOwnerNamespace: Equal(testNamespace),
+			constants.LabelKeyOwner:          Equal("test-vdc"),
+		}))
+		Expect(secret.Data[constants.TokenSecretKey]).To(HaveLen(64))
+
+		By("checking to copy the token to the namespace of the VirtualDC")
+		userSecret := &corev1.Secret{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc-token", Namespace: testNamespace}, userSecret)
+		}).Should(Succeed())
+		Expect(userSecret.Data[constants.TokenSecretKey]).To(Equal(secret.Data[constants.TokenSecretKey]))
+		Expect(userSecret.OwnerReferences).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Kind": Equal("VirtualDC"),
+			"Name": Equal("test-vdc"),
+		})))
+
+		By("checking to mount the token in the pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+		Expect(pod.Spec.Volumes).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name": Equal(constants.TokenVolumeName),
+			"VolumeSource": MatchFields(IgnoreExtras, Fields{
+				"Secret": PointTo(MatchFields(IgnoreExtras, Fields{"SecretName": Equal("test-vdc")})),
+			}),
+		})))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      constants.TokenVolumeName,
+			MountPath: constants.TokenMountPath,
+			ReadOnly:  true,
+		}))
+		Expect(pod.Spec.Containers[0].Args).To(ContainElements("--terminal", "--token-file=/var/run/nyamber/token"))
+
+		By("deleting the VirtualDC")
+		err = k8sClient.Delete(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, secret)
+			return apierrors.IsNotFound(err)
+		}).Should(BeTrue())
+	})
+
+	It("should merge the scheduling constraints set by VirtualDC spec into the pod template", func() {
+		By("setting scheduling constraints to the pod template")
+		cm := &corev1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: constants.ControllerNamespace, Name: constants.PodTemplateName}, cm)
+		Expect(err).NotTo(HaveOccurred())
+		cm.Data = map[string]string{"pod-template": `apiVersion: v1
+kind: Pod
+spec:
+  nodeSelector:
+    kubernetes.io/os: linux
+    cybozu.com/kvm: "false"
+  tolerations:
+    - key: node.kubernetes.io/unreachable
+      operator: Exists
+      effect: NoExecute
+  affinity:
+    podAntiAffinity:
+      preferredDuringSchedulingIgnoredDuringExecution:
+        - weight: 100
+          podAffinityTerm:
+            topologyKey: kubernetes.io/hostname
+            labelSelector:
+              matchLabels:
+                app.kubernetes.io/name: nyamber
+  containers:
+    - image: nyamber-runner:envtest
+      name: ubuntu`}
+		err = k8sClient.Update(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("creating a VirtualDC resource")
+		nodeAffinity := &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "cybozu.com/memory", Operator: corev1.NodeSelectorOpGt, Values: []string{"256"}}},
+				}},
+			},
+		}
+		toleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "dctest", Effect: corev1.TaintEffectNoSchedule}
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				NodeSelector: map[string]string{"cybozu.com/kvm": "true"},
+				Affinity:     &corev1.Affinity{NodeAffinity: nodeAffinity},
+				Tolerations:  []corev1.Toleration{toleration},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the scheduling constraints of the pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+		Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{
+			"kubernetes.io/os": "linux",
+			"cybozu.com/kvm":   "true",
+		}))
+		Expect(pod.Spec.Affinity.NodeAffinity).To(Equal(nodeAffinity))
+		Expect(pod.Spec.Affinity.PodAntiAffinity).NotTo(BeNil())
+		Expect(pod.Spec.Tolerations).To(ContainElements(
+			HaveField("Key", "node.kubernetes.io/unreachable"),
+			toleration,
+		))
+
+		By("reporting why the pod is not scheduled")
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: "0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector.",
+		}}
+		err = k8sClient.Status().Update(ctx, pod)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testNamespace}, vdc); err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(vdc.Status.Conditions, nyamberv1beta1.TypePodAvailable)
+			if cond == nil || cond.Reason != nyamberv1beta1.ReasonPodAvailableNotScheduled {
+				return fmt.Errorf("PodAvailable is expected to be NotScheduled, but actual %v", vdc.Status.Conditions)
+			}
+			if cond.Message != "Unschedulable: 0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector." {
+				return fmt.Errorf("unexpected message: %s", cond.Message)
+			}
+			return nil
+		}).Should(Succeed())
+	})
+
+	It("should merge the resources set by VirtualDC spec into the pod template", func() {
+		By("setting resources to the pod template")
+		cm := &corev1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: constants.ControllerNamespace, Name: constants.PodTemplateName}, cm)
+		Expect(err).NotTo(HaveOccurred())
+		cm.Data = map[string]string{"pod-template": `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+    - image: nyamber-runner:envtest
+      name: ubuntu
+      resources:
+        requests:
+          cpu: "1"
+          memory: 4Gi
+        limits:
+          memory: 8Gi`}
+		err = k8sClient.Update(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("creating a VirtualDC resource")
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the resources of the pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+		resources := pod.Spec.Containers[0].Resources
+		Expect(resources.Requests.Cpu().String()).To(Equal("1"))
+		Expect(resources.Requests.Memory().String()).To(Equal("16Gi"))
+		Expect(resources.Limits.Memory().String()).To(Equal("16Gi"))
+	})
+
+	It("should not create a pod when the resources are invalid", func() {
+		By("creating a VirtualDC resource whose requests exceed the limit in the pod template")
+		cm := &corev1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: constants.ControllerNamespace, Name: constants.PodTemplateName}, cm)
+		Expect(err).NotTo(HaveOccurred())
+		cm.Data = map[string]string{"pod-template": `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+    - image: nyamber-runner:envtest
+      name: ubuntu
+      resources:
+        limits:
+          memory: 8Gi`}
+		err = k8sClient.Update(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the condition of the VirtualDC")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testNamespace}, vdc); err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(vdc.Status.Conditions, nyamberv1beta1.TypePodCreated)
+			if cond == nil || cond.Reason != nyamberv1beta1.ReasonPodCreatedInvalidResources {
+				return fmt.Errorf("PodCreated is expected to be InvalidResources, but actual %v", vdc.Status.Conditions)
+			}
+			if cond.Message != "invalid resources: request of memory (16Gi) exceeds the limit (8Gi)" {
+				return fmt.Errorf("unexpected message: %s", cond.Message)
+			}
+			return nil
+		}).Should(Succeed())
+
+		By("checking not to create pod")
+		pod := &corev1.Pod{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("updating the pod template to exceed the maximum")
+		cm.Data = map[string]string{"pod-template": `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+    - image: nyamber-runner:envtest
+      name: ubuntu
+      resources:
+        limits:
+          memory: 64Gi`}
+		err = k8sClient.Update(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testNamespace}, vdc); err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(vdc.Status.Conditions, nyamberv1beta1.TypePodCreated)
+			if cond == nil || cond.Message != "invalid resources: limit of memory (64Gi) exceeds the maximum (32Gi)" {
+				return fmt.Errorf("unexpected conditions: %v", vdc.Status.Conditions)
+			}
+			return nil
+		}).Should(Succeed())
+	})
+
+	It("should copy the Secrets and the ConfigMaps referenced by env and envFrom", func() {
+		By("creating a Secret and a ConfigMap in the namespace of VirtualDC")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: testNamespace},
+			Type:       "example.com/token",
+			Data:       map[string][]byte{"token": []byte("ghp_test")},
+		}
+		err := k8sClient.Create(ctx, secret)
+		Expect(err).NotTo(HaveOccurred())
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "features", Namespace: testNamespace},
+			Data:       map[string]string{"FEATURE_A": "true"},
+		}
+		err = k8sClient.Create(ctx, configMap)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			err := k8sClient.Delete(ctx, configMap)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+
+		By("creating a VirtualDC resource")
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Env: []corev1.EnvVar{
+					{Name: "DEBUG", Value: "1"},
+					{Name: "GITHUB_TOKEN", ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "github"},
+							Key:                  "token",
+						},
+					}},
+				},
+				EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "features"}}},
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Optional: ptr.To(true)}},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the copies")
+		copiedSecret := &corev1.Secret{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.github", Namespace: testPodNamespace}, copiedSecret)
+		}).Should(Succeed())
+		Expect(copiedSecret.Type).To(Equal(secret.Type))
+		Expect(copiedSecret.Data).To(Equal(secret.Data))
+		Expect(copiedSecret.Labels).To(MatchAllKeys(Keys{
+			constants.LabelKeyOwnerNamespace: Equal(testNamespace),
+			constants.LabelKeyOwner:          Equal("test-vdc"),
+			constants.LabelKeyCopied:         Equal("true"),
+		}))
+		copiedConfigMap := &corev1.ConfigMap{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.features", Namespace: testPodNamespace}, copiedConfigMap)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(copiedConfigMap.Data).To(Equal(configMap.Data))
+
+		By("checking the environment variables of the pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+		container := pod.Spec.Containers[0]
+		Expect(container.Env).To(ContainElements(
+			corev1.EnvVar{Name: "DEBUG", Value: "1"},
+			HaveField("ValueFrom.SecretKeyRef.Name", "test-vdc.github"),
+		))
+		Expect(container.EnvFrom).To(ConsistOf(
+			HaveField("ConfigMapRef.Name", "test-vdc.features"),
+			HaveField("SecretRef.Name", "test-vdc.missing"),
+		))
+
+		By("updating the copy when the source is updated")
+		secret.Data = map[string][]byte{"token": []byte("ghp_updated")}
+		err = k8sClient.Update(ctx, secret)
+		Expect(err).NotTo(HaveOccurred())
+		// Trigger the reconciliation by updating the pod.
+		pod.Labels["test"] = "updated"
+		err = k8sClient.Update(ctx, pod)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() (string, error) {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.github", Namespace: testPodNamespace}, copiedSecret); err != nil {
+				return "", err
+			}
+			return string(copiedSecret.Data["token"]), nil
+		}).Should(Equal("ghp_updated"))
+
+		By("deleting the VirtualDC")
+		err = k8sClient.Delete(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.github", Namespace: testPodNamespace}, copiedSecret)
+			return apierrors.IsNotFound(err)
+		}).Should(BeTrue())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.features", Namespace: testPodNamespace}, copiedConfigMap)
+			return apierrors.IsNotFound(err)
+		}).Should(BeTrue())
+	})
+
+	It("should mount the copies of the ConfigMaps and keep them up to date", func() {
+		By("creating a ConfigMap in the namespace of VirtualDC")
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "scripts", Namespace: testNamespace},
+			Data:       map[string]string{"test.sh": "echo test"},
+		}
+		err := k8sClient.Create(ctx, configMap)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			err := k8sClient.Delete(ctx, configMap)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+
+		By("creating a VirtualDC resource")
+		items := []corev1.KeyToPath{{Key: "test.sh", Path: "bin/test.sh"}}
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				ConfigMapMounts: []nyamberv1beta1.ConfigMapMount{
+					{Name: "scripts", MountPath: "/opt/scripts", Items: items},
+					{Name: "overrides", MountPath: "/etc/neco", Optional: true},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the volumes of the pod")
+		pod := &corev1.Pod{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		}).Should(Succeed())
+		Expect(pod.Spec.Volumes).To(ContainElements(
+			corev1.Volume{
+				Name: "nyamber-configmap-0",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "test-vdc.scripts"},
+					Items:                items,
+					DefaultMode:          ptr.To[int32](corev1.ConfigMapVolumeSourceDefaultMode),
+				}},
+			},
+			corev1.Volume{
+				Name: "nyamber-configmap-1",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "test-vdc.overrides"},
+					DefaultMode:          ptr.To[int32](corev1.ConfigMapVolumeSourceDefaultMode),
+					Optional:             ptr.To(true),
+				}},
+			},
+		))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElements(
+			corev1.VolumeMount{Name: "nyamber-configmap-0", MountPath: "/opt/scripts", ReadOnly: true},
+			corev1.VolumeMount{Name: "nyamber-configmap-1", MountPath: "/etc/neco", ReadOnly: true},
+		))
+
+		By("checking the copy of the ConfigMap")
+		copied := &corev1.ConfigMap{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.scripts", Namespace: testPodNamespace}, copied)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(copied.Data).To(Equal(configMap.Data))
+
+		By("updating the ConfigMap")
+		configMap.Data = map[string]string{"test.sh": "echo updated"}
+		err = k8sClient.Update(ctx, configMap)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() (map[string]string, error) {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.scripts", Namespace: testPodNamespace}, copied); err != nil {
+				return nil, err
+			}
+			return copied.Data, nil
+		}).Should(Equal(configMap.Data))
+
+		By("creating the optional ConfigMap")
+		overrides := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "overrides", Namespace: testNamespace},
+			Data:       map[string]string{"config.yml": "foo: bar"},
+		}
+		err = k8sClient.Create(ctx, overrides)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			err := k8sClient.Delete(ctx, overrides)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.overrides", Namespace: testPodNamespace}, copied)
+		}).Should(Succeed())
+
+		By("restoring the copy edited in the namespace of the runner pod")
+		copied.Data = map[string]string{"config.yml": "edited"}
+		err = k8sClient.Update(ctx, copied)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() (map[string]string, error) {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc.overrides", Namespace: testPodNamespace}, copied); err != nil {
+				return nil, err
+			}
+			return copied.Data, nil
+		}).Should(Equal(overrides.Data))
+
+		By("deleting the VirtualDC")
+		err = k8sClient.Delete(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() ([]corev1.ConfigMap, error) {
+			cms := &corev1.ConfigMapList{}
+			if err := k8sClient.List(ctx, cms, client.InNamespace(testPodNamespace), client.MatchingLabels{constants.LabelKeyCopied: "true"}); err != nil {
+				return nil, err
+			}
+			return cms.Items, nil
+		}).Should(BeEmpty())
+	})
+
+	It("should not create a pod when a ConfigMap is mounted over a volume of the pod template", func() {
+		By("creating a VirtualDC resource which mounts a ConfigMap under a volume of the pod template")
+		cm := &corev1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: constants.ControllerNamespace, Name: constants.PodTemplateName}, cm)
+		Expect(err).NotTo(HaveOccurred())
+		cm.Data = map[string]string{"pod-template": `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+    - image: nyamber-runner:envtest
+      name: ubuntu
+      volumeMounts:
+      - name: entrypoint-state
+        mountPath: /var/lib/entrypoint
+  volumes:
+  - name: entrypoint-state
+    emptyDir: {}`}
+		err = k8sClient.Update(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				ConfigMapMounts: []nyamberv1beta1.ConfigMapMount{
+					{Name: "state", MountPath: "/var/lib/entrypoint/state", Optional: true},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the condition of the VirtualDC")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testNamespace}, vdc); err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(vdc.Status.Conditions, nyamberv1beta1.TypePodCreated)
+			if cond == nil || cond.Reason != nyamberv1beta1.ReasonPodCreatedInvalidMounts {
+				return fmt.Errorf("PodCreated is expected to be InvalidMounts, but actual %v", vdc.Status.Conditions)
+			}
+			if cond.Message != "invalid mount paths: /var/lib/entrypoint/state overlaps with /var/lib/entrypoint of volume entrypoint-state" {
+				return fmt.Errorf("unexpected message: %s", cond.Message)
+			}
+			return nil
+		}).Should(Succeed())
+
+		By("checking not to create pod")
+		pod := &corev1.Pod{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should not create a pod when a Secret referenced by env does not exist", func() {
+		By("creating a VirtualDC resource")
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				EnvFrom: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}}},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the condition of the VirtualDC")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testNamespace}, vdc); err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(vdc.Status.Conditions, nyamberv1beta1.TypePodCreated)
+			if cond == nil || cond.Reason != nyamberv1beta1.ReasonPodCreatedCopyFailed {
+				return fmt.Errorf("PodCreated is expected to be CopyFailed, but actual %v", vdc.Status.Conditions)
+			}
+			return nil
+		}).Should(Succeed())
+
+		By("checking not to create pod")
+		pod := &corev1.Pod{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "test-vdc", Namespace: testPodNamespace}, pod)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
 	It("should not create a pod when the wrong configmap was created", func() {
 		By("creating wrong configmap")
 		cm := &corev1.ConfigMap{}
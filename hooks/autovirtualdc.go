@@ -22,6 +22,7 @@ import (
 
 	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
 	"github.com/robfig/cron"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -32,14 +33,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-func SetupAutoVirtualDCWebhookWithManager(mgr ctrl.Manager) error {
+// SetupAutoVirtualDCWebhookWithManager registers the webhook for AutoVirtualDC.
+// maxResources is the maximum of the resources of the runner container, which may be nil.
+func SetupAutoVirtualDCWebhookWithManager(mgr ctrl.Manager, maxResources corev1.ResourceList) error {
 	return ctrl.NewWebhookManagedBy(mgr, &nyamberv1beta1.AutoVirtualDC{}).
-		WithValidator(&autoVirtualdcValidator{client: mgr.GetClient()}).
+		WithValidator(&autoVirtualdcValidator{client: mgr.GetClient(), maxResources: maxResources}).
 		Complete()
 }
 
 type autoVirtualdcValidator struct {
-	client client.Client
+	client       client.Client
+	maxResources corev1.ResourceList
 }
 
 //+kubebuilder:webhook:path=/validate-nyamber-cybozu-io-v1beta1-autovirtualdc,mutating=false,failurePolicy=fail,sideEffects=None,groups=nyamber.cybozu.io,resources=autovirtualdcs,verbs=create;update,versions=v1beta1,name=vautovirtualdc.kb.io,admissionReviewVersions=v1
@@ -51,6 +55,7 @@ func (v autoVirtualdcValidator) ValidateCreate(ctx context.Context, avdc *nyambe
 
 	errs := v.validateTimeoutDuration(avdc)
 	errs = append(errs, v.validateSchedule(avdc)...)
+	errs = append(errs, validateVirtualDCSpec(field.NewPath("spec", "template", "spec"), &avdc.Spec.Template.Spec, v.maxResources)...)
 
 	vdcs := &nyamberv1beta1.VirtualDCList{}
 	if err := v.client.List(ctx, vdcs); err != nil {
@@ -89,6 +94,7 @@ func (v autoVirtualdcValidator) ValidateUpdate(ctx context.Context, oldAvdc, new
 	logger.Info("validate update", "name", newAvdc.Name)
 
 	errs := v.validateTimeoutDuration(newAvdc)
+	errs = append(errs, validateVirtualDCSpec(field.NewPath("spec", "template", "spec"), &newAvdc.Spec.Template.Spec, v.maxResources)...)
 
 	oldSpec := oldAvdc.Spec
 	newSpec := newAvdc.Spec
@@ -0,0 +1,65 @@
+package hooks
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateConfigMapMounts validates the ConfigMaps mounted in the runner container.
+// The mount paths must not overlap with each other or with the volumes of the token and the jobs file,
+// because the API server rejects only the duplicate paths.
+func validateConfigMapMounts(p *field.Path, spec *nyamberv1beta1.VirtualDCSpec) field.ErrorList {
+	var errs field.ErrorList
+	mountPaths := []string{constants.TokenMountPath, constants.JobsMountPath}
+	for i, mount := range spec.ConfigMapMounts {
+		mp := p.Child("configMapMounts").Index(i)
+		errs = append(errs, validateSourceName(mp.Child("name"), mount.Name)...)
+
+		switch {
+		case mount.MountPath == "":
+			errs = append(errs, field.Required(mp.Child("mountPath"), ""))
+		case !path.IsAbs(mount.MountPath):
+			errs = append(errs, field.Invalid(mp.Child("mountPath"), mount.MountPath, "must be an absolute path"))
+		case path.Clean(mount.MountPath) == "/":
+			errs = append(errs, field.Invalid(mp.Child("mountPath"), mount.MountPath, "must not be the root directory"))
+		default:
+			mountPath := path.Clean(mount.MountPath)
+			if slices.ContainsFunc(mountPaths, func(other string) bool { return isSubPath(mountPath, other) || isSubPath(other, mountPath) }) {
+				errs = append(errs, field.Invalid(mp.Child("mountPath"), mount.MountPath, "must not overlap with the other mount paths, "+constants.TokenMountPath+" or "+constants.JobsMountPath))
+			}
+			mountPaths = append(mountPaths, mountPath)
+		}
+
+		var itemPaths []string
+		for j, item := range mount.Items {
+			ip := mp.Child("items").Index(j)
+			errs = append(errs, validateSourceKey(ip.Child("key"), item.Key)...)
+			switch {
+			case item.Path == "":
+				errs = append(errs, field.Required(ip.Child("path"), ""))
+			case path.IsAbs(item.Path):
+				errs = append(errs, field.Invalid(ip.Child("path"), item.Path, "must be a relative path"))
+			case slices.Contains(strings.Split(item.Path, "/"), ".."):
+				errs = append(errs, field.Invalid(ip.Child("path"), item.Path, "must not contain '..'"))
+			case slices.Contains(itemPaths, path.Clean(item.Path)):
+				errs = append(errs, field.Duplicate(ip.Child("path"), item.Path))
+			default:
+				itemPaths = append(itemPaths, path.Clean(item.Path))
+			}
+			if item.Mode != nil && (*item.Mode < 0 || *item.Mode > 0777) {
+				errs = append(errs, field.Invalid(ip.Child("mode"), *item.Mode, "must be a number between 0 and 0777 (octal)"))
+			}
+		}
+	}
+	return errs
+}
+
+// isSubPath returns true if p is dir or a path under dir. Both paths must be clean.
+func isSubPath(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
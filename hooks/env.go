@@ -0,0 +1,122 @@
+package hooks
+
+import (
+	"slices"
+	"strings"
+
+	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
+	"github.com/cybozu-go/nyamber/pkg/entrypoint"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// reservedEnvNames are the environment variables which the controller sets to the runner container.
+// The variables with entrypoint.EnvNamePrefix are also reserved because the runner sets them to the jobs.
+var reservedEnvNames = []string{"NECO_BRANCH", "NECO_APPS_BRANCH"}
+
+func isReservedEnvName(name string) bool {
+	return slices.Contains(reservedEnvNames, name) || strings.HasPrefix(name, entrypoint.EnvNamePrefix)
+}
+
+// validateEnv validates env and envFrom of the runner container.
+// The Secrets and the ConfigMaps are copied with their names, so the names are required here
+// though the API server allows empty names in the pod.
+func validateEnv(path *field.Path, spec *nyamberv1beta1.VirtualDCSpec) field.ErrorList {
+	var errs field.ErrorList
+	for i, env := range spec.Env {
+		p := path.Child("env").Index(i)
+		errs = append(errs, validateEnvVarName(p.Child("name"), env.Name)...)
+		if isReservedEnvName(env.Name) {
+			errs = append(errs, field.Forbidden(p.Child("name"), "the variable is set by the controller or the runner"))
+		}
+		if env.ValueFrom != nil {
+			errs = append(errs, validateEnvVarSource(p, &env)...)
+		}
+	}
+
+	for i, envFrom := range spec.EnvFrom {
+		p := path.Child("envFrom").Index(i)
+		if envFrom.Prefix != "" {
+			errs = append(errs, validateEnvVarName(p.Child("prefix"), envFrom.Prefix)...)
+			if strings.HasPrefix(envFrom.Prefix, entrypoint.EnvNamePrefix) {
+				errs = append(errs, field.Forbidden(p.Child("prefix"), "the variables with the prefix "+entrypoint.EnvNamePrefix+" are set by the runner"))
+			}
+		}
+		switch {
+		case envFrom.ConfigMapRef != nil && envFrom.SecretRef != nil:
+			errs = append(errs, field.Invalid(p, "", "may not have more than one source"))
+		case envFrom.ConfigMapRef != nil:
+			errs = append(errs, validateSourceName(p.Child("configMapRef", "name"), envFrom.ConfigMapRef.Name)...)
+		case envFrom.SecretRef != nil:
+			errs = append(errs, validateSourceName(p.Child("secretRef", "name"), envFrom.SecretRef.Name)...)
+		default:
+			errs = append(errs, field.Required(p, "must specify one of configMapRef or secretRef"))
+		}
+	}
+	return errs
+}
+
+func validateEnvVarSource(path *field.Path, env *corev1.EnvVar) field.ErrorList {
+	var errs field.ErrorList
+	if env.Value != "" {
+		errs = append(errs, field.Invalid(path.Child("valueFrom"), "", "may not be specified when value is not empty"))
+	}
+
+	source := env.ValueFrom
+	p := path.Child("valueFrom")
+	count := 0
+	if source.FieldRef != nil {
+		count++
+	}
+	if source.ResourceFieldRef != nil {
+		count++
+	}
+	if ref := source.ConfigMapKeyRef; ref != nil {
+		count++
+		errs = append(errs, validateSourceName(p.Child("configMapKeyRef", "name"), ref.Name)...)
+		errs = append(errs, validateSourceKey(p.Child("configMapKeyRef", "key"), ref.Key)...)
+	}
+	if ref := source.SecretKeyRef; ref != nil {
+		count++
+		errs = append(errs, validateSourceName(p.Child("secretKeyRef", "name"), ref.Name)...)
+		errs = append(errs, validateSourceKey(p.Child("secretKeyRef", "key"), ref.Key)...)
+	}
+	if count != 1 {
+		errs = append(errs, field.Invalid(p, "", "must have exactly one of fieldRef, resourceFieldRef, configMapKeyRef or secretKeyRef"))
+	}
+	return errs
+}
+
+func validateEnvVarName(path *field.Path, name string) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsEnvVarName(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	return errs
+}
+
+func validateSourceName(path *field.Path, name string) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	return errs
+}
+
+func validateSourceKey(path *field.Path, key string) field.ErrorList {
+	if key == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsConfigMapKey(key) {
+		errs = append(errs, field.Invalid(path, key, msg))
+	}
+	return errs
+}
@@ -0,0 +1,166 @@
+package hooks
+
+import (
+	"strconv"
+
+	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateScheduling validates the scheduling constraints of the runner pod.
+// It checks what the API server would reject when the controller creates the pod, so that such VirtualDC is rejected earlier.
+func validateScheduling(path *field.Path, spec *nyamberv1beta1.VirtualDCSpec) field.ErrorList {
+	errs := metav1validation.ValidateLabels(spec.NodeSelector, path.Child("nodeSelector"))
+
+	for i, toleration := range spec.Tolerations {
+		errs = append(errs, validateToleration(path.Child("tolerations").Index(i), &toleration)...)
+	}
+
+	if affinity := spec.Affinity; affinity != nil {
+		p := path.Child("affinity")
+		if na := affinity.NodeAffinity; na != nil {
+			if required := na.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+				errs = append(errs, validateNodeSelector(p.Child("nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution"), required)...)
+			}
+			for i, term := range na.PreferredDuringSchedulingIgnoredDuringExecution {
+				tp := p.Child("nodeAffinity", "preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+				errs = append(errs, validateWeight(tp.Child("weight"), term.Weight)...)
+				errs = append(errs, validateNodeSelectorTerm(tp.Child("preference"), &term.Preference)...)
+			}
+		}
+		if pa := affinity.PodAffinity; pa != nil {
+			errs = append(errs, validatePodAffinityTerms(p.Child("podAffinity"), pa.RequiredDuringSchedulingIgnoredDuringExecution, pa.PreferredDuringSchedulingIgnoredDuringExecution)...)
+		}
+		if paa := affinity.PodAntiAffinity; paa != nil {
+			errs = append(errs, validatePodAffinityTerms(p.Child("podAntiAffinity"), paa.RequiredDuringSchedulingIgnoredDuringExecution, paa.PreferredDuringSchedulingIgnoredDuringExecution)...)
+		}
+	}
+	return errs
+}
+
+func validateToleration(path *field.Path, toleration *corev1.Toleration) field.ErrorList {
+	var errs field.ErrorList
+	if toleration.Key != "" {
+		errs = append(errs, metav1validation.ValidateLabelName(toleration.Key, path.Child("key"))...)
+	}
+
+	switch toleration.Operator {
+	case corev1.TolerationOpEqual, "":
+		if toleration.Key == "" {
+			errs = append(errs, field.Invalid(path.Child("operator"), toleration.Operator, "operator must be Exists when key is empty"))
+		}
+		for _, msg := range validation.IsValidLabelValue(toleration.Value) {
+			errs = append(errs, field.Invalid(path.Child("value"), toleration.Value, msg))
+		}
+	case corev1.TolerationOpExists:
+		if toleration.Value != "" {
+			errs = append(errs, field.Invalid(path.Child("value"), toleration.Value, "value must be empty when operator is Exists"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("operator"), toleration.Operator, []corev1.TolerationOperator{corev1.TolerationOpEqual, corev1.TolerationOpExists}))
+	}
+
+	switch toleration.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("effect"), toleration.Effect, []corev1.TaintEffect{corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute}))
+	}
+	if toleration.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+		errs = append(errs, field.Invalid(path.Child("effect"), toleration.Effect, "effect must be NoExecute when tolerationSeconds is set"))
+	}
+	return errs
+}
+
+func validateNodeSelector(path *field.Path, selector *corev1.NodeSelector) field.ErrorList {
+	if len(selector.NodeSelectorTerms) == 0 {
+		return field.ErrorList{field.Required(path.Child("nodeSelectorTerms"), "must have at least one node selector term")}
+	}
+	var errs field.ErrorList
+	for i, term := range selector.NodeSelectorTerms {
+		errs = append(errs, validateNodeSelectorTerm(path.Child("nodeSelectorTerms").Index(i), &term)...)
+	}
+	return errs
+}
+
+func validateNodeSelectorTerm(path *field.Path, term *corev1.NodeSelectorTerm) field.ErrorList {
+	var errs field.ErrorList
+	for i, req := range term.MatchExpressions {
+		p := path.Child("matchExpressions").Index(i)
+		errs = append(errs, metav1validation.ValidateLabelName(req.Key, p.Child("key"))...)
+		errs = append(errs, validateNodeSelectorRequirement(p, &req)...)
+	}
+	for i, req := range term.MatchFields {
+		p := path.Child("matchFields").Index(i)
+		if req.Key != "metadata.name" {
+			errs = append(errs, field.NotSupported(p.Child("key"), req.Key, []string{"metadata.name"}))
+		}
+		if req.Operator != corev1.NodeSelectorOpIn && req.Operator != corev1.NodeSelectorOpNotIn {
+			errs = append(errs, field.NotSupported(p.Child("operator"), req.Operator, []corev1.NodeSelectorOperator{corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn}))
+		} else if len(req.Values) != 1 {
+			errs = append(errs, field.Invalid(p.Child("values"), req.Values, "must have one value"))
+		}
+	}
+	return errs
+}
+
+func validateNodeSelectorRequirement(path *field.Path, req *corev1.NodeSelectorRequirement) field.ErrorList {
+	var errs field.ErrorList
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+		if len(req.Values) == 0 {
+			errs = append(errs, field.Required(path.Child("values"), "must be specified when operator is In or NotIn"))
+		}
+	case corev1.NodeSelectorOpExists, corev1.NodeSelectorOpDoesNotExist:
+		if len(req.Values) > 0 {
+			errs = append(errs, field.Forbidden(path.Child("values"), "may not be specified when operator is Exists or DoesNotExist"))
+		}
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if len(req.Values) != 1 {
+			errs = append(errs, field.Required(path.Child("values"), "must be specified as one value when operator is Gt or Lt"))
+		} else if _, err := strconv.ParseInt(req.Values[0], 10, 64); err != nil {
+			errs = append(errs, field.Invalid(path.Child("values").Index(0), req.Values[0], "must be an integer"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("operator"), req.Operator, []corev1.NodeSelectorOperator{
+			corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn, corev1.NodeSelectorOpExists,
+			corev1.NodeSelectorOpDoesNotExist, corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt,
+		}))
+	}
+	return errs
+}
+
+func validatePodAffinityTerms(path *field.Path, required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm) field.ErrorList {
+	var errs field.ErrorList
+	for i, term := range required {
+		errs = append(errs, validatePodAffinityTerm(path.Child("requiredDuringSchedulingIgnoredDuringExecution").Index(i), &term)...)
+	}
+	for i, term := range preferred {
+		p := path.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+		errs = append(errs, validateWeight(p.Child("weight"), term.Weight)...)
+		errs = append(errs, validatePodAffinityTerm(p.Child("podAffinityTerm"), &term.PodAffinityTerm)...)
+	}
+	return errs
+}
+
+func validatePodAffinityTerm(path *field.Path, term *corev1.PodAffinityTerm) field.ErrorList {
+	var errs field.ErrorList
+	opts := metav1validation.LabelSelectorValidationOptions{}
+	errs = append(errs, metav1validation.ValidateLabelSelector(term.LabelSelector, opts, path.Child("labelSelector"))...)
+	errs = append(errs, metav1validation.ValidateLabelSelector(term.NamespaceSelector, opts, path.Child("namespaceSelector"))...)
+	if term.TopologyKey == "" {
+		errs = append(errs, field.Required(path.Child("topologyKey"), "can not be empty"))
+	} else {
+		errs = append(errs, metav1validation.ValidateLabelName(term.TopologyKey, path.Child("topologyKey"))...)
+	}
+	return errs
+}
+
+func validateWeight(path *field.Path, weight int32) field.ErrorList {
+	if weight < 1 || weight > 100 {
+		return field.ErrorList{field.Invalid(path, weight, "must be in the range 1-100")}
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ import (
 	"go.uber.org/zap/zapcore"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -50,6 +51,12 @@ const (
 	testAnotherNamespace string = "another-ns"
 )
 
+// testMaxResources is the maximum of the resources of the runner container.
+var testMaxResources = corev1.ResourceList{
+	corev1.ResourceCPU:    resource.MustParse("8"),
+	corev1.ResourceMemory: resource.MustParse("32Gi"),
+}
+
 var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
@@ -113,9 +120,9 @@ var _ = BeforeSuite(func() {
 		LeaderElection: false,
 	})
 	Expect(err).NotTo(HaveOccurred())
-	err = SetupAutoVirtualDCWebhookWithManager(mgr)
+	err = SetupAutoVirtualDCWebhookWithManager(mgr, testMaxResources)
 	Expect(err).NotTo(HaveOccurred())
-	err = SetupVirtualDCWebhookWithManager(mgr)
+	err = SetupVirtualDCWebhookWithManager(mgr, testMaxResources)
 	Expect(err).NotTo(HaveOccurred())
 
 	//+kubebuilder:scaffold:webhook
@@ -18,8 +18,13 @@ package hooks
 
 import (
 	"context"
+	"slices"
 
 	nyamberv1beta1 "github.com/cybozu-go/nyamber/api/v1beta1"
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/cybozu-go/nyamber/pkg/entrypoint"
+	"github.com/cybozu-go/nyamber/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -30,16 +35,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-func SetupVirtualDCWebhookWithManager(mgr ctrl.Manager) error {
+// SetupVirtualDCWebhookWithManager registers the webhook for VirtualDC.
+// maxResources is the maximum of the resources of the runner container, which may be nil.
+func SetupVirtualDCWebhookWithManager(mgr ctrl.Manager, maxResources corev1.ResourceList) error {
 	return ctrl.NewWebhookManagedBy(mgr, &nyamberv1beta1.VirtualDC{}).
-		WithValidator(&virtualdcValidator{client: mgr.GetClient()}).
+		WithValidator(&virtualdcValidator{client: mgr.GetClient(), maxResources: maxResources}).
 		Complete()
 }
 
 //+kubebuilder:webhook:path=/validate-nyamber-cybozu-io-v1beta1-virtualdc,mutating=false,failurePolicy=fail,sideEffects=None,groups=nyamber.cybozu.io,resources=virtualdcs,verbs=create;update,versions=v1beta1,name=vvirtualdc.kb.io,admissionReviewVersions=v1
 
 type virtualdcValidator struct {
-	client client.Client
+	client       client.Client
+	maxResources corev1.ResourceList
 }
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
@@ -79,6 +87,8 @@ func (v virtualdcValidator) ValidateCreate(ctx context.Context, vdc *nyamberv1be
 		}
 	}
 
+	errs = append(errs, validateVirtualDCSpec(field.NewPath("spec"), &vdc.Spec, v.maxResources)...)
+
 	if len(errs) > 0 {
 		err := apierrors.NewInvalid(schema.GroupKind{Group: nyamberv1beta1.GroupVersion.Group, Kind: "VirtualDC"}, vdc.Name, errs)
 		logger.Error(err, "validation error", "name", vdc.Name)
@@ -113,10 +123,62 @@ func (v virtualdcValidator) ValidateUpdate(ctx context.Context, oldVdc, newVdc *
 		errs = append(errs, field.Forbidden(field.NewPath("spec", "command"), "the field is immutable"))
 	}
 
+	if !equality.Semantic.DeepEqual(oldSpec.Jobs, newSpec.Jobs) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "jobs"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.Env, newSpec.Env) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "env"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.EnvFrom, newSpec.EnvFrom) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "envFrom"), "the field is immutable"))
+	}
+
 	if !equality.Semantic.DeepEqual(oldSpec.Resources, newSpec.Resources) {
 		errs = append(errs, field.Forbidden(field.NewPath("spec", "resources"), "the field is immutable"))
 	}
 
+	if !equality.Semantic.DeepEqual(oldSpec.NodeSelector, newSpec.NodeSelector) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "nodeSelector"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.Affinity, newSpec.Affinity) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "affinity"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.Tolerations, newSpec.Tolerations) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "tolerations"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.JobTimeouts, newSpec.JobTimeouts) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "jobTimeouts"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.JobRetries, newSpec.JobRetries) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "jobRetries"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.TestReports, newSpec.TestReports) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "testReports"), "the field is immutable"))
+	}
+
+	if oldSpec.Terminal != newSpec.Terminal {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "terminal"), "the field is immutable"))
+	}
+
+	if oldSpec.ResumePolicy != newSpec.ResumePolicy {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "resumePolicy"), "the field is immutable"))
+	}
+
+	if oldSpec.ReadyJob != newSpec.ReadyJob {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "readyJob"), "the field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(oldSpec.ConfigMapMounts, newSpec.ConfigMapMounts) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "configMapMounts"), "the field is immutable"))
+	}
+
 	if len(errs) > 0 {
 		err := apierrors.NewInvalid(schema.GroupKind{Group: nyamberv1beta1.GroupVersion.Group, Kind: "VirtualDC"}, vdcName, errs)
 		logger.Error(err, "validation error", "name", vdcName)
@@ -126,6 +188,97 @@ func (v virtualdcValidator) ValidateUpdate(ctx context.Context, oldVdc, newVdc *
 	return nil, nil
 }
 
+func validateVirtualDCSpec(path *field.Path, spec *nyamberv1beta1.VirtualDCSpec, maxResources corev1.ResourceList) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validateEnv(path, spec)...)
+	errs = append(errs, validateConfigMapMounts(path, spec)...)
+	errs = append(errs, validateScheduling(path, spec)...)
+
+	// The resources are validated again with the pod template when the pod is created.
+	for _, violation := range resources.Validate(spec.Resources, maxResources) {
+		errs = append(errs, field.Invalid(path.Child("resources"), spec.Resources, violation))
+	}
+
+	jobNames := []string{constants.JobNameNecoBootstrap, constants.JobNameNecoAppsBootstrap, constants.JobNameUserDefinedCommand}
+	if len(spec.Jobs) != 0 && len(spec.Command) != 0 {
+		errs = append(errs, field.Forbidden(path.Child("jobs"), "may not be specified with command"))
+	}
+	var userJobNames []string
+	for i, job := range spec.Jobs {
+		p := path.Child("jobs").Index(i)
+		switch {
+		case !entrypoint.IsValidJobName(job.Name):
+			errs = append(errs, field.Invalid(p.Child("name"), job.Name, "must start with a letter and consist of letters, digits, '-' and '_'"))
+		case job.Name == constants.JobNameNecoBootstrap || job.Name == constants.JobNameNecoAppsBootstrap:
+			errs = append(errs, field.Invalid(p.Child("name"), job.Name, "the name is used by the bootstrap job"))
+		case slices.Contains(userJobNames, job.Name):
+			errs = append(errs, field.Duplicate(p.Child("name"), job.Name))
+		default:
+			userJobNames = append(userJobNames, job.Name)
+		}
+		if len(job.Command) == 0 || job.Command[0] == "" {
+			errs = append(errs, field.Required(p.Child("command"), "command must not be empty"))
+		}
+	}
+	for _, name := range userJobNames {
+		if !slices.Contains(jobNames, name) {
+			jobNames = append(jobNames, name)
+		}
+	}
+	// validateJobName returns an error if the job of name does not run.
+	// The options of such a job would be ignored by the controller.
+	validateJobName := func(p *field.Path, name string) *field.Error {
+		switch {
+		case !slices.Contains(jobNames, name):
+			return field.NotSupported(p, name, jobNames)
+		case name == constants.JobNameNecoAppsBootstrap && spec.SkipNecoApps:
+			return field.Invalid(p, name, "the job does not run when skipNecoApps is true")
+		case name == constants.JobNameUserDefinedCommand && len(spec.Command) == 0 && !slices.Contains(userJobNames, name):
+			return field.Invalid(p, name, "the job does not run when command is empty")
+		}
+		return nil
+	}
+
+	for name, timeout := range spec.JobTimeouts {
+		p := path.Child("jobTimeouts").Key(name)
+		if err := validateJobName(p, name); err != nil {
+			errs = append(errs, err)
+		}
+		if timeout.Duration <= 0 {
+			errs = append(errs, field.Invalid(p, timeout.Duration.String(), "timeout must be positive"))
+		}
+	}
+
+	for name, retries := range spec.JobRetries {
+		p := path.Child("jobRetries").Key(name)
+		if err := validateJobName(p, name); err != nil {
+			errs = append(errs, err)
+		}
+		if retries < 0 {
+			errs = append(errs, field.Invalid(p, retries, "retries must not be negative"))
+		}
+	}
+
+	for name, reportPath := range spec.TestReports {
+		p := path.Child("testReports").Key(name)
+		if err := validateJobName(p, name); err != nil {
+			errs = append(errs, err)
+		}
+		if reportPath == "" {
+			errs = append(errs, field.Required(p, "path must not be empty"))
+		}
+	}
+
+	if spec.ReadyJob != "" {
+		if err := validateJobName(path.Child("readyJob"), spec.ReadyJob); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (v virtualdcValidator) ValidateDelete(ctx context.Context, vdc *nyamberv1beta1.VirtualDC) (warnings admission.Warnings, err error) {
 	return nil, nil
@@ -212,5 +212,428 @@ var _ = Describe("VirtualDC validator", func() {
 		newVdc.Spec.Resources.Limits[corev1.ResourceCPU] = resource.MustParse("200m")
 		err = k8sClient.Update(ctx, newVdc)
 		Expect(err).To(HaveOccurred())
+
+		By("updating JobTimeouts")
+		newVdc = vdc.DeepCopy()
+		newVdc.Spec.JobTimeouts = map[string]metav1.Duration{"neco_bootstrap": {Duration: time.Hour}}
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+
+		By("updating JobRetries")
+		newVdc = vdc.DeepCopy()
+		newVdc.Spec.JobRetries = map[string]int32{"neco_bootstrap": 1}
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+
+		By("updating TestReports")
+		newVdc = vdc.DeepCopy()
+		newVdc.Spec.TestReports = map[string]string{"user_defined_command": "report.xml"}
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+
+		By("updating Terminal")
+		newVdc = vdc.DeepCopy()
+		newVdc.Spec.Terminal = true
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+
+		By("updating ResumePolicy")
+		newVdc = vdc.DeepCopy()
+		newVdc.Spec.ResumePolicy = "Interrupt"
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+
+		By("updating ReadyJob")
+		newVdc = vdc.DeepCopy()
+		newVdc.Spec.ReadyJob = "neco_bootstrap"
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should validate job timeouts", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				JobTimeouts: map[string]metav1.Duration{
+					"unknown_job": {Duration: time.Hour},
+				},
+			},
+		}
+		By("creating a virtualdc with a timeout of an unknown job")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a non-positive timeout")
+		vdc.Spec.JobTimeouts = map[string]metav1.Duration{
+			"neco_bootstrap": {Duration: 0},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a timeout of a job skipped by skipNecoApps")
+		vdc.Spec.SkipNecoApps = true
+		vdc.Spec.JobTimeouts = map[string]metav1.Duration{
+			"neco_apps_bootstrap": {Duration: time.Hour},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a timeout of a job without command")
+		vdc.Spec.JobTimeouts = map[string]metav1.Duration{
+			"user_defined_command": {Duration: 30 * time.Minute},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid timeouts")
+		vdc.Spec.Command = []string{"test", "command"}
+		vdc.Spec.JobTimeouts = map[string]metav1.Duration{
+			"neco_bootstrap":       {Duration: 2 * time.Hour},
+			"user_defined_command": {Duration: 30 * time.Minute},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should validate job retries", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				JobRetries: map[string]int32{
+					"unknown_job": 1,
+				},
+			},
+		}
+		By("creating a virtualdc with retries of an unknown job")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with negative retries")
+		vdc.Spec.JobRetries = map[string]int32{
+			"neco_bootstrap": -1,
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with retries of a job skipped by skipNecoApps")
+		vdc.Spec.SkipNecoApps = true
+		vdc.Spec.JobRetries = map[string]int32{
+			"neco_apps_bootstrap": 1,
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid retries")
+		vdc.Spec.SkipNecoApps = false
+		vdc.Spec.JobRetries = map[string]int32{
+			"neco_bootstrap":      2,
+			"neco_apps_bootstrap": 1,
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should validate test reports", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				TestReports: map[string]string{
+					"unknown_job": "report.xml",
+				},
+			},
+		}
+		By("creating a virtualdc with a test report of an unknown job")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a test report of a job without command")
+		vdc.Spec.TestReports = map[string]string{
+			"user_defined_command": "/tmp/report.xml",
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with an empty path")
+		vdc.Spec.Command = []string{"test", "command"}
+		vdc.Spec.TestReports = map[string]string{
+			"user_defined_command": "",
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid test reports")
+		vdc.Spec.TestReports = map[string]string{
+			"user_defined_command": "/tmp/report.xml",
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should validate the ready job", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				SkipNecoApps: true,
+				ReadyJob:     "neco_apps_bootstrap",
+			},
+		}
+		By("creating a virtualdc with a ready job skipped by skipNecoApps")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a ready job without command")
+		vdc.Spec.ReadyJob = "user_defined_command"
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a valid ready job")
+		vdc.Spec.ReadyJob = "neco_bootstrap"
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should validate resources", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+				},
+			},
+		}
+		By("creating a virtualdc whose requests exceed the limits")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc whose resources exceed the maximum")
+		vdc.Spec.Resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Gi")},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid resources")
+		vdc.Spec.Resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Gi")},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should validate scheduling constraints", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				NodeSelector: map[string]string{"invalid key!": "true"},
+			},
+		}
+		By("creating a virtualdc with an invalid node selector")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with an invalid toleration")
+		vdc.Spec.NodeSelector = nil
+		vdc.Spec.Tolerations = []corev1.Toleration{{Operator: corev1.TolerationOpExists, Value: "dctest"}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with an invalid affinity")
+		vdc.Spec.Tolerations = nil
+		vdc.Spec.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "cybozu.com/kvm", Operator: corev1.NodeSelectorOpIn}},
+					}},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid scheduling constraints")
+		vdc.Spec.NodeSelector = map[string]string{"cybozu.com/kvm": "true"}
+		vdc.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "dctest", Effect: corev1.TaintEffectNoSchedule}}
+		vdc.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values = []string{"true"}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("updating the scheduling constraints")
+		newVdc := vdc.DeepCopy()
+		newVdc.Spec.NodeSelector = map[string]string{"cybozu.com/kvm": "false"}
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should validate env and envFrom", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Env: []corev1.EnvVar{{Name: "NECO_BRANCH", Value: "release"}},
+			},
+		}
+		By("creating a virtualdc which overrides the variable set by the controller")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc which overrides the variable set by the runner")
+		vdc.Spec.Env = []corev1.EnvVar{{Name: "NYAMBER_TOKEN", Value: "token"}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc whose envFrom has the prefix of the variables set by the runner")
+		vdc.Spec.Env = nil
+		vdc.Spec.EnvFrom = []corev1.EnvFromSource{{
+			Prefix:       "NYAMBER_OUTPUT_",
+			ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "features"}},
+		}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+		vdc.Spec.EnvFrom = nil
+
+		By("creating a virtualdc whose env refers to a Secret without the name")
+		vdc.Spec.Env = []corev1.EnvVar{{Name: "GITHUB_TOKEN", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{Key: "token"},
+		}}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc whose envFrom has no source")
+		vdc.Spec.Env = nil
+		vdc.Spec.EnvFrom = []corev1.EnvFromSource{{Prefix: "FEATURE_"}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid env and envFrom")
+		vdc.Spec.Env = []corev1.EnvVar{{Name: "GITHUB_TOKEN", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "github"}, Key: "token"},
+		}}}
+		vdc.Spec.EnvFrom = []corev1.EnvFromSource{{
+			Prefix:       "FEATURE_",
+			ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "features"}},
+		}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("updating env")
+		newVdc := vdc.DeepCopy()
+		newVdc.Spec.Env = append(newVdc.Spec.Env, corev1.EnvVar{Name: "DEBUG", Value: "1"})
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should validate ConfigMap mounts", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				ConfigMapMounts: []nyamberv1beta1.ConfigMapMount{{Name: "scripts", MountPath: "scripts"}},
+			},
+		}
+		By("creating a virtualdc with a relative mount path")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc whose mount paths overlap")
+		vdc.Spec.ConfigMapMounts = []nyamberv1beta1.ConfigMapMount{
+			{Name: "scripts", MountPath: "/opt/scripts"},
+			{Name: "tools", MountPath: "/opt/scripts/tools"},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc which mounts a key outside of the mount path")
+		vdc.Spec.ConfigMapMounts = []nyamberv1beta1.ConfigMapMount{
+			{Name: "scripts", MountPath: "/opt/scripts", Items: []corev1.KeyToPath{{Key: "test.sh", Path: "../test.sh"}}},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid ConfigMap mounts")
+		vdc.Spec.ConfigMapMounts = []nyamberv1beta1.ConfigMapMount{
+			{Name: "scripts", MountPath: "/opt/scripts", Items: []corev1.KeyToPath{{Key: "test.sh", Path: "bin/test.sh"}}},
+			{Name: "overrides", MountPath: "/etc/neco", Optional: true},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("updating the ConfigMap mounts")
+		newVdc := vdc.DeepCopy()
+		newVdc.Spec.ConfigMapMounts[1].MountPath = "/etc/neco-overrides"
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should validate jobs", func() {
+		vdc := &nyamberv1beta1.VirtualDC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-vdc",
+				Namespace: testNamespace,
+			},
+			Spec: nyamberv1beta1.VirtualDCSpec{
+				Command: []string{"/scripts/test"},
+				Jobs:    []nyamberv1beta1.Job{{Name: "deploy", Command: []string{"/scripts/deploy"}}},
+			},
+		}
+		By("creating a virtualdc with both command and jobs")
+		err := k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with a job named after a bootstrap job")
+		vdc.Spec.Command = nil
+		vdc.Spec.Jobs = []nyamberv1beta1.Job{{Name: "neco_bootstrap", Command: []string{"/scripts/deploy"}}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with duplicate job names")
+		vdc.Spec.Jobs = []nyamberv1beta1.Job{
+			{Name: "deploy", Command: []string{"/scripts/deploy"}},
+			{Name: "deploy", Command: []string{"/scripts/deploy-again"}},
+		}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc whose readyJob is not run")
+		vdc.Spec.Jobs = []nyamberv1beta1.Job{{Name: "deploy", Command: []string{"/scripts/deploy"}}}
+		vdc.Spec.ReadyJob = "run_tests"
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).To(HaveOccurred())
+
+		By("creating a virtualdc with valid jobs")
+		vdc.Spec.Jobs = append(vdc.Spec.Jobs, nyamberv1beta1.Job{Name: "run_tests", Command: []string{"sh", "-c", "make test"}})
+		vdc.Spec.JobTimeouts = map[string]metav1.Duration{"run_tests": {Duration: time.Hour}}
+		err = k8sClient.Create(ctx, vdc)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("updating jobs")
+		newVdc := vdc.DeepCopy()
+		newVdc.Spec.Jobs = newVdc.Spec.Jobs[:1]
+		err = k8sClient.Update(ctx, newVdc)
+		Expect(err).To(HaveOccurred())
 	})
 })
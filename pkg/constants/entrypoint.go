@@ -3,3 +3,28 @@ package constants
 const ListenPort = 8080
 
 const StatusEndPoint = "status"
+
+const LogsEndPoint = "logs"
+
+const EventsEndPoint = "events"
+
+const CancelEndPoint = "cancel"
+
+const RerunEndPoint = "rerun"
+
+const MetricsEndPoint = "metrics"
+
+const HealthzEndPoint = "healthz"
+
+const ReadyzEndPoint = "readyz"
+
+const ProgressEndPoint = "progress"
+
+const TerminalEndPoint = "terminal"
+
+// Names of the jobs run in the runner pod.
+const (
+	JobNameNecoBootstrap      = "neco_bootstrap"
+	JobNameNecoAppsBootstrap  = "neco_apps_bootstrap"
+	JobNameUserDefinedCommand = "user_defined_command"
+)
@@ -6,6 +6,9 @@ const MetaPrefix = "nyamber.cybozu.io/"
 const (
 	LabelKeyOwnerNamespace = MetaPrefix + "owner-namespace"
 	LabelKeyOwner          = MetaPrefix + "owner"
+
+	// LabelKeyCopied is a label key for the Secrets and the ConfigMaps copied from the namespace of VirtualDC.
+	LabelKeyCopied = MetaPrefix + "copied"
 )
 
 const FinalizerName = MetaPrefix + "finalizer"
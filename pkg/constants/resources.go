@@ -5,3 +5,47 @@ const ControllerNamespace = "nyamber"
 const PodNamespace = "nyamber-runner"
 
 const PodTemplateName = "nyamber-pod-template"
+
+// Token to access the runner API.
+const (
+	// TokenSecretSuffix is the suffix of the name of the Secret which has the token in the namespace of VirtualDC.
+	TokenSecretSuffix = "-token"
+
+	// TokenSecretKey is the key of the token in the Secret.
+	TokenSecretKey = "token"
+
+	// TokenVolumeName is the name of the volume of the token in the runner pod.
+	TokenVolumeName = "nyamber-token"
+
+	// TokenMountPath is the path where the token volume is mounted in the runner container.
+	TokenMountPath = "/var/run/nyamber"
+)
+
+// Jobs file given to the entrypoint when VirtualDC has spec.jobs.
+const (
+	// JobsFileKey is the key of the jobs file in the ConfigMap named after VirtualDC in the namespace of the runner pod.
+	JobsFileKey = "jobs.json"
+
+	// JobsVolumeName is the name of the volume of the jobs file in the runner pod.
+	JobsVolumeName = "nyamber-jobs"
+
+	// JobsMountPath is the path where the jobs file volume is mounted in the runner container.
+	JobsMountPath = "/etc/nyamber"
+)
+
+// ConfigMapVolumeNamePrefix is the prefix of the names of the volumes of the ConfigMaps mounted by VirtualDC.
+// It is followed by the index in spec.configMapMounts.
+const ConfigMapVolumeNamePrefix = "nyamber-configmap-"
+
+// Notification of the changes of the job states from the runner pods to the controller.
+const (
+	// NotifyPort is the port where the controller receives the notifications.
+	NotifyPort = 8082
+
+	// NotifyServiceName is the name of the Service for the runner pods to send the notifications to the controller.
+	NotifyServiceName = "nyamber-controller-notify"
+
+	// NotifyEndPoint is the endpoint of the controller to receive the notifications.
+	// The path is followed by the namespace and the name of VirtualDC.
+	NotifyEndPoint = "notify"
+)
@@ -0,0 +1,76 @@
+package entrypoint
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/gorilla/websocket"
+)
+
+// TokenEnvName is the name of the environment variable which has the token for clients of the runner API.
+// The runner sets it for the jobs so that they can report their progress.
+const TokenEnvName = "NYAMBER_TOKEN"
+
+// readToken reads the token from the file. The file is read every time so that the token can be rotated.
+func readToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// requestToken returns the token given by the Authorization header.
+// The token query parameter is also accepted for a websocket upgrade of the terminal API and a GET of the events API,
+// because browsers cannot set headers of a websocket request or an EventSource.
+// It is not accepted for the other APIs so that the token does not leak through access logs or browser histories.
+func requestToken(req *http.Request) string {
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	switch {
+	case req.URL.Path == "/"+constants.TerminalEndPoint && websocket.IsWebSocketUpgrade(req):
+		return req.URL.Query().Get("token")
+	case req.URL.Path == "/"+constants.EventsEndPoint && req.Method == http.MethodGet:
+		return req.URL.Query().Get("token")
+	}
+	return ""
+}
+
+// authenticate returns a handler which calls h only if the request has the token in TokenFile.
+// If TokenFile is not set, it calls h for every request.
+func (r *Runner) authenticate(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.options.TokenFile == "" {
+			h(w, req)
+			return
+		}
+		token, err := readToken(r.options.TokenFile)
+		if err != nil {
+			r.logger.Error(err, "failed to read token", "token_file", r.options.TokenFile)
+			http.Error(w, "failed to read token", http.StatusInternalServerError)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(requestToken(req)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, req)
+	})
+}
+
+// setToken sets the token to the request if token is not empty.
+func setToken(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
@@ -0,0 +1,93 @@
+package entrypoint
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint authentication test", func() {
+	It("should require the token to access the API", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("secret\n"), 0600)).To(Succeed())
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "sh", Args: []string{"-c", "echo $NYAMBER_TOKEN"}},
+			{Name: "test2", Command: "sleep", Args: []string{"30"}},
+		}, RunnerOptions{TokenFile: tokenFile})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(func() (int, error) { return getHealthStatusCode(constants.HealthzEndPoint) }, 10, 0.5).Should(Equal(http.StatusOK))
+
+		By("accessing the API without the token")
+		for _, path := range []string{
+			constants.StatusEndPoint,
+			constants.LogsEndPoint + "/test1",
+			constants.EventsEndPoint,
+		} {
+			Expect(requestAPI(http.MethodGet, path, "")).To(Equal(http.StatusUnauthorized), path)
+			Expect(requestAPI(http.MethodGet, path, "wrong")).To(Equal(http.StatusUnauthorized), path)
+		}
+		Expect(requestAPI(http.MethodPost, constants.CancelEndPoint+"/test2", "")).To(Equal(http.StatusUnauthorized))
+
+		By("accessing the health checks and the metrics without the token")
+		Expect(requestAPI(http.MethodGet, constants.ReadyzEndPoint, "")).To(Equal(http.StatusOK))
+		Expect(requestAPI(http.MethodGet, constants.MetricsEndPoint, "")).To(Equal(http.StatusOK))
+
+		By("accessing the API with the token")
+		Expect(requestAPI(http.MethodGet, constants.StatusEndPoint, "secret")).To(Equal(http.StatusOK))
+		Expect(requestAPI(http.MethodGet, constants.StatusEndPoint+"?token=secret", "")).To(Equal(http.StatusUnauthorized))
+		Expect(requestAPI(http.MethodGet, constants.EventsEndPoint+"?token=secret", "")).To(Equal(http.StatusOK))
+		Expect(requestAPI(http.MethodGet, constants.EventsEndPoint+"?token=wrong", "")).To(Equal(http.StatusUnauthorized))
+
+		By("passing the token to the jobs")
+		Eventually(func() (string, error) { return getLogsWithToken("test1", "secret") }, 10, 0.5).Should(Equal("secret\n"))
+
+		By("rotating the token")
+		Expect(os.WriteFile(tokenFile, []byte("rotated\n"), 0600)).To(Succeed())
+		Expect(requestAPI(http.MethodGet, constants.StatusEndPoint, "secret")).To(Equal(http.StatusUnauthorized))
+		Expect(requestAPI(http.MethodGet, constants.StatusEndPoint, "rotated")).To(Equal(http.StatusOK))
+	})
+
+	It("should reject the terminal without a token file", func() {
+		_, err := NewRunner(":0", log, []Job{{Name: "test1", Command: "true"}}, RunnerOptions{Terminal: true})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func requestAPI(method, path, token string) (int, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), path), nil)
+	if err != nil {
+		return 0, err
+	}
+	setToken(req, token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func getLogsWithToken(jobName, token string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.LogsEndPoint, jobName), nil)
+	if err != nil {
+		return "", err
+	}
+	setToken(req, token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
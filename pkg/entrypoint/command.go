@@ -0,0 +1,99 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cybozu-go/well"
+	"golang.org/x/sys/unix"
+)
+
+// waitDelay is the time to wait for the output of a killed job to be closed.
+const waitDelay = 10 * time.Second
+
+// jobCommand is a command to run a job in its own process group.
+type jobCommand struct {
+	*well.LogCmd
+
+	mu sync.Mutex
+	// exited is true once Run has returned, i.e. the process group leader has been reaped.
+	exited    bool
+	killTimer *time.Timer
+	killed    bool
+}
+
+// newJobCommand prepares a command to run the job in its own process group.
+// When ctx is done, SIGTERM is sent to the whole process group so that the job can clean up,
+// and SIGKILL is sent to the process group after gracePeriod unless the job has exited by then.
+func newJobCommand(ctx context.Context, job Job, out io.Writer, gracePeriod time.Duration) *jobCommand {
+	cmd := &jobCommand{LogCmd: well.CommandContext(ctx, job.Command, job.Args...)}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Dir = job.WorkingDir
+	if len(job.Env) > 0 {
+		cmd.Env = append(os.Environ(), job.Env...)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		cmd.mu.Lock()
+		defer cmd.mu.Unlock()
+		if cmd.exited {
+			return nil
+		}
+		cmd.killTimer = time.AfterFunc(gracePeriod, func() {
+			cmd.mu.Lock()
+			defer cmd.mu.Unlock()
+			// The pgid may be reused by another process group once the leader has been reaped.
+			if cmd.exited {
+				return
+			}
+			cmd.killed = true
+			syscall.Kill(-pgid, syscall.SIGKILL)
+		})
+		return syscall.Kill(-pgid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = gracePeriod + waitDelay
+	return cmd
+}
+
+// Run runs the command and stops sending SIGKILL to the process group after the command has exited.
+func (c *jobCommand) Run() error {
+	err := c.LogCmd.Run()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exited = true
+	if c.killTimer != nil {
+		c.killTimer.Stop()
+	}
+	return err
+}
+
+// exitStatus returns the exit code of the process or the name of the signal which terminated it.
+// It returns nil and an empty string if the process did not start.
+func exitStatus(state *os.ProcessState) (*int, string) {
+	if state == nil {
+		return nil, ""
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return nil, unix.SignalName(ws.Signal())
+	}
+	code := state.ExitCode()
+	return &code, ""
+}
+
+// failureMessage returns a short description of the failure of a job.
+func failureMessage(err error, exitCode *int, signal string) string {
+	switch {
+	case signal != "":
+		return fmt.Sprintf("killed by signal %s", signal)
+	case exitCode != nil && *exitCode != 0:
+		return fmt.Sprintf("exit code %d", *exitCode)
+	}
+	return err.Error()
+}
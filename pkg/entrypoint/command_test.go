@@ -0,0 +1,115 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint job termination test", func() {
+	It("should terminate jobs gracefully", func() {
+		cancel := startRunnerWithOptions([]Job{
+			{
+				Name:      "cleanup",
+				Command:   "sh",
+				Args:      []string{"-c", `trap "echo cleanup; exit 0" TERM; sleep 30 & wait`},
+				Timeout:   time.Second,
+				DependsOn: []string{},
+			},
+			{
+				Name:      "ignore",
+				Command:   "sh",
+				Args:      []string{"-c", `trap "" TERM; sleep 30`},
+				Timeout:   time.Second,
+				DependsOn: []string{},
+			},
+		}, RunnerOptions{GracePeriod: 2 * time.Second})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "cleanup", Status: "TimedOut"}, {Name: "ignore", Status: "TimedOut"}},
+		}))
+
+		By("checking the job cleaned up after SIGTERM")
+		Expect(getLogs("cleanup", "")).To(Equal("cleanup\n"))
+
+		By("checking the job ignoring SIGTERM was killed after the grace period")
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[1].Signal).To(Equal("SIGKILL"))
+		startTime, err := time.Parse(time.RFC3339, resp.Jobs[1].StartTime)
+		Expect(err).NotTo(HaveOccurred())
+		endTime, err := time.Parse(time.RFC3339, resp.Jobs[1].EndTime)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endTime.Sub(startTime)).To(BeNumerically(">=", 2*time.Second))
+	})
+
+	It("should run finally jobs on shutdown", func() {
+		dir := GinkgoT().TempDir()
+		marker := filepath.Join(dir, "marker")
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "sleep", Args: []string{"30"}},
+			{Name: "test2", Command: "true", Args: []string{}},
+			{Name: "cleanup", Command: "touch", Args: []string{marker}, Finally: true},
+		}, RunnerOptions{StateDir: dir})
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Running"}, {Name: "test2", Status: "Pending"}, {Name: "cleanup", Status: "Pending"}},
+		}))
+
+		cancel()
+		Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		Eventually(marker, 10, 0.5).Should(BeAnExistingFile())
+
+		var states []JobState
+		Eventually(func(g Gomega) {
+			data, err := os.ReadFile(filepath.Join(dir, stateFileName))
+			g.Expect(err).NotTo(HaveOccurred())
+			resp := &StatusResponse{}
+			g.Expect(json.Unmarshal(data, resp)).To(Succeed())
+			states = resp.Jobs
+			g.Expect(states[2].Status).To(Equal(JobStatusCompleted))
+		}, 10, 0.5).Should(Succeed())
+		Expect(states[0].Status).To(Equal(JobStatusTerminated))
+		Expect(states[0].Signal).To(Equal("SIGTERM"))
+		Expect(states[0].Message).To(Equal(shutdownMessage))
+		Expect(states[1].Status).To(Equal(JobStatusTerminated))
+		Expect(states[1].Message).To(Equal(shutdownMessage))
+
+		By("resuming the terminated jobs after a restart")
+		cancel = startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+			{Name: "test2", Command: "true", Args: []string{}},
+			{Name: "cleanup", Command: "touch", Args: []string{marker}, Finally: true},
+		}, RunnerOptions{StateDir: dir})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Completed"}, {Name: "cleanup", Status: "Completed"}},
+		}))
+	})
+
+	It("should not kill the process group after the job has exited", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cmd := newJobCommand(ctx, Job{
+			Command: "sh",
+			Args:    []string{"-c", `trap "exit 0" TERM; sleep 30 & wait`},
+		}, io.Discard, time.Second)
+		time.AfterFunc(500*time.Millisecond, cancel)
+		Expect(cmd.Run()).To(MatchError(context.Canceled))
+
+		time.Sleep(2 * time.Second)
+		cmd.mu.Lock()
+		defer cmd.mu.Unlock()
+		Expect(cmd.killed).To(BeFalse())
+	})
+})
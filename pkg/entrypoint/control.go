@@ -0,0 +1,162 @@
+package entrypoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+)
+
+// errJobCancelled is the cause of the context of a job cancelled by a request.
+var errJobCancelled = errors.New("job is cancelled")
+
+const (
+	cancelledMessage = "cancelled by request"
+	shutdownMessage  = "cancelled by shutdown of the runner"
+)
+
+// isCancelled returns true if the job running with ctx is cancelled by a request.
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errJobCancelled)
+}
+
+// jobIndex returns the index of the job with the name.
+func (r *Runner) jobIndex(name string) (int, bool) {
+	for i, job := range r.jobs {
+		if job.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// cancelJob cancels the i-th job if it is running or pending.
+// A running job is killed and becomes Cancelled after it exits.
+func (r *Runner) cancelJob(i int) error {
+	cancelled := r.updateJobStateIf(i, JobStatusPending, func(state *JobState) {
+		state.Status = JobStatusCancelled
+		state.Message = cancelledMessage
+	})
+	if cancelled {
+		r.wakeUp()
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.jobStates[i].Status != JobStatusRunning || r.cancels[i] == nil {
+		return fmt.Errorf("job %s is %s", r.jobs[i].Name, r.jobStates[i].Status)
+	}
+	r.cancels[i](errJobCancelled)
+	return nil
+}
+
+// rerunJob resets the i-th job and its dependents to Pending so that they run again.
+// It fails if any of the jobs is running.
+func (r *Runner) rerunJob(i int) error {
+	targets := r.dependents(i)
+
+	r.mutex.Lock()
+	for _, j := range targets {
+		if r.jobStates[j].Status == JobStatusRunning {
+			r.mutex.Unlock()
+			return fmt.Errorf("job %s is running", r.jobs[j].Name)
+		}
+	}
+	states := make([]JobState, 0, len(targets))
+	for _, j := range targets {
+		r.jobStates[j] = initialJobState(r.jobs[j])
+		states = append(states, r.jobStates[j])
+	}
+	snapshot := r.snapshotState()
+	r.mutex.Unlock()
+	r.recordState(snapshot)
+
+	for _, state := range states {
+		r.events.publish(event{
+			name: eventStatus,
+			job:  state.Name,
+			data: state,
+		})
+	}
+	r.notify()
+	r.logger.Info("rerun job", "job_name", r.jobs[i].Name)
+	r.wakeUp()
+	return nil
+}
+
+// dependents returns the indices of the i-th job and the jobs depending on it directly or indirectly.
+// Finally jobs run after the other jobs, so they are included unless the i-th job is a finally job.
+func (r *Runner) dependents(i int) []int {
+	marked := make([]bool, len(r.jobs))
+	marked[i] = true
+	if !r.jobs[i].Finally {
+		for j, job := range r.jobs {
+			if job.Finally {
+				marked[j] = true
+			}
+		}
+	}
+	for progress := true; progress; {
+		progress = false
+		for j, deps := range r.deps {
+			if marked[j] {
+				continue
+			}
+			for _, k := range deps {
+				if marked[k] {
+					marked[j] = true
+					progress = true
+					break
+				}
+			}
+		}
+	}
+
+	var indices []int
+	for j := range marked {
+		if marked[j] {
+			indices = append(indices, j)
+		}
+	}
+	return indices
+}
+
+// wakeUp notifies the scheduler that the states of jobs are changed.
+func (r *Runner) wakeUp() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Runner) cancelHandler(w http.ResponseWriter, req *http.Request) {
+	r.controlHandler(w, req, constants.CancelEndPoint, r.cancelJob)
+}
+
+func (r *Runner) rerunHandler(w http.ResponseWriter, req *http.Request) {
+	r.controlHandler(w, req, constants.RerunEndPoint, r.rerunJob)
+}
+
+// controlHandler applies the operation to the job named in the path of the request.
+func (r *Runner) controlHandler(w http.ResponseWriter, req *http.Request, endpoint string, op func(i int) error) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobName := strings.TrimPrefix(req.URL.Path, "/"+endpoint+"/")
+	i, ok := r.jobIndex(jobName)
+	if !ok {
+		http.Error(w, fmt.Sprintf("job %q is not found", jobName), http.StatusNotFound)
+		return
+	}
+	if err := op(i); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
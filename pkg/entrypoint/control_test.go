@@ -0,0 +1,124 @@
+package entrypoint
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint control API test", func() {
+	It("should cancel jobs", func() {
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "sleep", Args: []string{"30"}},
+			{Name: "test2", Command: "true", Args: []string{}},
+			{Name: "test3", Command: "sleep", Args: []string{"30"}, DependsOn: []string{}},
+			{Name: "test4", Command: "true", Args: []string{}, DependsOn: []string{"test3"}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{
+				{Name: "test1", Status: "Running"},
+				{Name: "test2", Status: "Pending"},
+				{Name: "test3", Status: "Running"},
+				{Name: "test4", Status: "Pending"},
+			},
+		}))
+
+		By("cancelling a running job")
+		Expect(postControl(constants.CancelEndPoint, "test1")).To(Equal(http.StatusAccepted))
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{
+				{Name: "test1", Status: "Cancelled"},
+				{Name: "test2", Status: "Skipped"},
+				{Name: "test3", Status: "Running"},
+				{Name: "test4", Status: "Pending"},
+			},
+		}))
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Message).To(Equal(cancelledMessage))
+		Expect(resp.Jobs[1].Message).To(Equal("dependency test1 is Cancelled"))
+
+		By("cancelling a pending job")
+		Expect(postControl(constants.CancelEndPoint, "test4")).To(Equal(http.StatusAccepted))
+		Expect(getStatus()).To(Equal(&statusResponse{
+			Jobs: []job{
+				{Name: "test1", Status: "Cancelled"},
+				{Name: "test2", Status: "Skipped"},
+				{Name: "test3", Status: "Running"},
+				{Name: "test4", Status: "Cancelled"},
+			},
+		}))
+
+		By("requesting invalid operations")
+		Expect(postControl(constants.CancelEndPoint, "test1")).To(Equal(http.StatusConflict))
+		Expect(postControl(constants.RerunEndPoint, "test3")).To(Equal(http.StatusConflict))
+		Expect(postControl(constants.CancelEndPoint, "unknown")).To(Equal(http.StatusNotFound))
+		res, err := http.Get(controlURL(constants.CancelEndPoint, "test3"))
+		Expect(err).NotTo(HaveOccurred())
+		res.Body.Close()
+		Expect(res.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should rerun a job and the jobs depending on it", func() {
+		marker := filepath.Join(GinkgoT().TempDir(), "marker")
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+			{Name: "test2", Command: "test", Args: []string{"-f", marker}},
+			{Name: "test3", Command: "true", Args: []string{}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{
+				{Name: "test1", Status: "Completed"},
+				{Name: "test2", Status: "Failed"},
+				{Name: "test3", Status: "Skipped"},
+			},
+		}))
+		before, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("rerunning the failed job after fixing it")
+		Expect(os.WriteFile(marker, nil, 0644)).To(Succeed())
+		Expect(postControl(constants.RerunEndPoint, "test2")).To(Equal(http.StatusAccepted))
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{
+				{Name: "test1", Status: "Completed"},
+				{Name: "test2", Status: "Completed"},
+				{Name: "test3", Status: "Completed"},
+			},
+		}))
+
+		By("checking the first job did not rerun")
+		after, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after.Jobs[0]).To(Equal(before.Jobs[0]))
+		Expect(after.Jobs[1].Attempts).To(HaveLen(1))
+		Expect(after.Jobs[1].Message).To(BeEmpty())
+	})
+})
+
+func controlURL(endpoint, jobName string) string {
+	return fmt.Sprintf("http://%s/%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), endpoint, jobName)
+}
+
+func postControl(endpoint, jobName string) (int, error) {
+	resp, err := http.Post(controlURL(endpoint, jobName), "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
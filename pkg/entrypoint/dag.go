@@ -0,0 +1,85 @@
+package entrypoint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveDependencies returns the indices of the jobs which each job depends on.
+// A job whose DependsOn is nil depends on the previous job of the same kind.
+// It returns an error if the jobs have invalid or duplicated names, unknown dependencies, dependencies on finally jobs
+// from other jobs, or cycles.
+func resolveDependencies(jobs []Job) ([][]int, error) {
+	index := make(map[string]int, len(jobs))
+	for i, job := range jobs {
+		if !IsValidJobName(job.Name) {
+			return nil, fmt.Errorf("invalid job name %q", job.Name)
+		}
+		if _, ok := index[job.Name]; ok {
+			return nil, fmt.Errorf("duplicated job name %s", job.Name)
+		}
+		index[job.Name] = i
+	}
+
+	deps := make([][]int, len(jobs))
+	previous := map[bool]int{false: -1, true: -1}
+	for i, job := range jobs {
+		if job.DependsOn == nil {
+			if j := previous[job.Finally]; j >= 0 {
+				deps[i] = []int{j}
+			}
+			previous[job.Finally] = i
+			continue
+		}
+		previous[job.Finally] = i
+		deps[i] = make([]int, 0, len(job.DependsOn))
+		for _, name := range job.DependsOn {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("job %s depends on unknown job %s", job.Name, name)
+			}
+			if j == i {
+				return nil, fmt.Errorf("job %s depends on itself", job.Name)
+			}
+			if jobs[j].Finally && !job.Finally {
+				return nil, fmt.Errorf("job %s depends on finally job %s", job.Name, name)
+			}
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	// Detect cycles by depth-first search.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(jobs))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case visiting:
+			return fmt.Errorf("jobs have a dependency cycle: %s", strings.Join(append(path, jobs[i].Name), " -> "))
+		case visited:
+			return nil
+		}
+		marks[i] = visiting
+		path = append(path, jobs[i].Name)
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[i] = visited
+		return nil
+	}
+	for i := range jobs {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return deps, nil
+}
@@ -0,0 +1,192 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	eventStatus = "status"
+	eventLog    = "log"
+
+	// subscriberBufferSize is the number of events buffered for each subscriber.
+	// A subscriber which cannot keep up with the events is disconnected.
+	subscriberBufferSize = 1024
+
+	keepAliveInterval = 30 * time.Second
+)
+
+// LogEvent is the data of a "log" event.
+type LogEvent struct {
+	Job  string `json:"job"`
+	Time string `json:"time"`
+	Line string `json:"line"`
+}
+
+type event struct {
+	name string
+	job  string
+	seq  uint64
+	data any
+}
+
+// broker delivers events to the subscribers.
+type broker struct {
+	mu   sync.Mutex
+	subs map[chan event]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{
+		subs: make(map[chan event]struct{}),
+	}
+}
+
+func (b *broker) subscribe() chan event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan event, subscriberBufferSize)
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *broker) publish(ev event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (r *Runner) publishLog(jobName string, line logLine) {
+	r.events.publish(event{
+		name: eventLog,
+		job:  jobName,
+		seq:  line.Seq,
+		data: &LogEvent{
+			Job:  jobName,
+			Time: line.Time.Format(time.RFC3339Nano),
+			Line: line.Text,
+		},
+	})
+}
+
+func (r *Runner) eventsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobName := req.URL.Query().Get("job")
+	if jobName != "" {
+		if _, ok := r.logs[jobName]; !ok {
+			http.Error(w, fmt.Sprintf("job %q is not found", jobName), http.StatusNotFound)
+			return
+		}
+	}
+	since, tail, err := parseLogQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before taking the snapshot so that no event is lost.
+	// Log lines included in the snapshot are skipped by their sequence numbers.
+	ch := r.events.subscribe()
+	defer r.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	lastSeq := make(map[string]uint64)
+	for _, state := range r.getJobStates() {
+		if jobName != "" && state.Name != jobName {
+			continue
+		}
+		if err := writeEvent(w, eventStatus, state); err != nil {
+			return
+		}
+		for _, line := range r.logs[state.Name].Lines(since, tail) {
+			if line.Seq == 0 {
+				continue
+			}
+			lastSeq[state.Name] = line.Seq
+			ev := &LogEvent{Job: state.Name, Time: line.Time.Format(time.RFC3339Nano), Line: line.Text}
+			if err := writeEvent(w, eventLog, ev); err != nil {
+				return
+			}
+		}
+		if jobName != "" && isFinished(state.Status) {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-ch:
+			if !ok {
+				// The subscriber was too slow and has been disconnected.
+				return
+			}
+			if jobName != "" && ev.job != jobName {
+				continue
+			}
+			if ev.name == eventLog && ev.seq <= lastSeq[ev.job] {
+				continue
+			}
+			if err := writeEvent(w, ev.name, ev.data); err != nil {
+				return
+			}
+			flusher.Flush()
+			if state, ok := ev.data.(JobState); ok && jobName != "" && isFinished(state.Status) {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, name string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+	return err
+}
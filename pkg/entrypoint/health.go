@@ -0,0 +1,37 @@
+package entrypoint
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// healthzHandler reports that the entrypoint is alive.
+func (r *Runner) healthzHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler reports whether the environment built by the jobs is ready.
+// If ReadyJob is not configured, the entrypoint is ready once the HTTP server is up.
+// Otherwise, it is ready only while the job has completed.
+func (r *Runner) readyzHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.options.ReadyJob != "" {
+		i, _ := r.jobIndex(r.options.ReadyJob)
+		r.mutex.Lock()
+		status := r.jobStates[i].Status
+		r.mutex.Unlock()
+		if status != JobStatusCompleted {
+			http.Error(w, fmt.Sprintf("job %s is %s", r.options.ReadyJob, status), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,63 @@
+package entrypoint
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint health test", func() {
+	It("should be ready once the server is up without a ready job", func() {
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "sleep", Args: []string{"30"}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(func() (int, error) { return getHealthStatusCode(constants.HealthzEndPoint) }, 10, 0.5).Should(Equal(http.StatusOK))
+		Expect(getHealthStatusCode(constants.ReadyzEndPoint)).To(Equal(http.StatusOK))
+	})
+
+	It("should be ready while the ready job has completed", func() {
+		marker := filepath.Join(GinkgoT().TempDir(), "marker")
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "test", Args: []string{"-f", marker}},
+			{Name: "test2", Command: "sleep", Args: []string{"30"}},
+		}, RunnerOptions{ReadyJob: "test1"})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Failed"}, {Name: "test2", Status: "Skipped"}},
+		}))
+		Expect(getHealthStatusCode(constants.HealthzEndPoint)).To(Equal(http.StatusOK))
+		Expect(getHealthStatusCode(constants.ReadyzEndPoint)).To(Equal(http.StatusServiceUnavailable))
+
+		By("rerunning the ready job")
+		Expect(os.WriteFile(marker, nil, 0644)).To(Succeed())
+		Expect(postControl(constants.RerunEndPoint, "test1")).To(Equal(http.StatusAccepted))
+		Eventually(func() (int, error) { return getHealthStatusCode(constants.ReadyzEndPoint) }, 10, 0.5).Should(Equal(http.StatusOK))
+	})
+
+	It("should reject an unknown ready job", func() {
+		_, err := NewRunner("localhost:0", log, []Job{{Name: "test1", Command: "true"}}, RunnerOptions{ReadyJob: "test2"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func getHealthStatusCode(endpoint string) (int, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), endpoint))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
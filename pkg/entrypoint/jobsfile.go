@@ -0,0 +1,144 @@
+package entrypoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+var reJobName = regexp.MustCompile("^[a-zA-Z][-_a-zA-Z0-9]*$")
+
+// IsValidJobName returns true if name can be used as a job name.
+func IsValidJobName(name string) bool {
+	return reJobName.MatchString(name)
+}
+
+// JobsFile is the content of a jobs file given to the entrypoint.
+// A jobs file is written in YAML or JSON.
+type JobsFile struct {
+	Jobs []JobSpec `json:"jobs"`
+}
+
+// JobSpec is the definition of a job in a jobs file.
+type JobSpec struct {
+	// Name is the name of the job.
+	Name string `json:"name"`
+
+	// Command is the command to run and its arguments.
+	Command []string `json:"command"`
+
+	// Env is the environment variables added to the job.
+	Env map[string]string `json:"env,omitempty"`
+
+	// WorkingDir is the working directory of the job.
+	WorkingDir string `json:"workingDir,omitempty"`
+
+	// Timeout is the maximum duration of the job, e.g. "2h".
+	Timeout string `json:"timeout,omitempty"`
+
+	// Retries is the number of times to retry the job when it fails or times out.
+	Retries int `json:"retries,omitempty"`
+
+	// RetryBackoff is the duration to wait before the first retry, e.g. "30s".
+	RetryBackoff string `json:"retryBackoff,omitempty"`
+
+	// DependsOn is the names of the jobs which must complete before this job starts.
+	// If this is null, the job depends on the previous job of the same kind.
+	DependsOn []string `json:"dependsOn"`
+
+	// Finally makes the job run after all the other jobs have finished, whether they have succeeded or not.
+	Finally bool `json:"finally,omitempty"`
+
+	// TestReport is the path of a JUnit XML or Ginkgo JSON report written by the job.
+	TestReport string `json:"testReport,omitempty"`
+}
+
+// LoadJobsFile reads the jobs from a jobs file.
+func LoadJobsFile(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJobsFile(data)
+}
+
+// ParseJobsFile parses the content of a jobs file.
+func ParseJobsFile(data []byte) ([]Job, error) {
+	file := &JobsFile{}
+	if err := yaml.UnmarshalStrict(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs file: %w", err)
+	}
+	if len(file.Jobs) == 0 {
+		return nil, errors.New("jobs file has no jobs")
+	}
+
+	jobs := make([]Job, 0, len(file.Jobs))
+	for i, spec := range file.Jobs {
+		job, err := spec.ToJob()
+		if err != nil {
+			return nil, fmt.Errorf("invalid job at index %d: %w", i, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ToJob converts the definition into a Job.
+func (s *JobSpec) ToJob() (Job, error) {
+	if !IsValidJobName(s.Name) {
+		return Job{}, fmt.Errorf("invalid job name %q", s.Name)
+	}
+	if len(s.Command) == 0 || s.Command[0] == "" {
+		return Job{}, fmt.Errorf("command of job %s is empty", s.Name)
+	}
+	if s.Retries < 0 {
+		return Job{}, fmt.Errorf("retries of job %s must not be negative", s.Name)
+	}
+
+	job := Job{
+		Name:       s.Name,
+		Command:    s.Command[0],
+		Args:       s.Command[1:],
+		WorkingDir: s.WorkingDir,
+		Retries:    s.Retries,
+		DependsOn:  s.DependsOn,
+		Finally:    s.Finally,
+		TestReport: s.TestReport,
+	}
+
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		job.Env = append(job.Env, k+"="+s.Env[k])
+	}
+
+	if s.Timeout != "" {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return Job{}, fmt.Errorf("invalid timeout of job %s: %w", s.Name, err)
+		}
+		if timeout <= 0 {
+			return Job{}, fmt.Errorf("timeout of job %s must be positive", s.Name)
+		}
+		job.Timeout = timeout
+	}
+	if s.RetryBackoff != "" {
+		backoff, err := time.ParseDuration(s.RetryBackoff)
+		if err != nil {
+			return Job{}, fmt.Errorf("invalid retry backoff of job %s: %w", s.Name, err)
+		}
+		if backoff <= 0 {
+			return Job{}, fmt.Errorf("retry backoff of job %s must be positive", s.Name)
+		}
+		job.RetryBackoff = backoff
+	}
+	return job, nil
+}
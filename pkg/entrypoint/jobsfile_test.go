@@ -0,0 +1,109 @@
+package entrypoint
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint jobs file test", func() {
+	It("should parse a jobs file", func() {
+		jobs, err := ParseJobsFile([]byte(`
+jobs:
+- name: test1
+  command: ["sh", "-c", "echo 'a b:c'"]
+  env:
+    FOO: foo
+    BAR: bar
+  workingDir: /tmp
+  timeout: 1h
+  retries: 2
+  retryBackoff: 30s
+  testReport: junit.xml
+- name: test2
+  command: ["true"]
+  dependsOn: []
+- name: test3
+  command: ["true"]
+  dependsOn: ["test1", "test2"]
+- name: cleanup
+  command: ["true"]
+  finally: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs).To(Equal([]Job{
+			{
+				Name:         "test1",
+				Command:      "sh",
+				Args:         []string{"-c", "echo 'a b:c'"},
+				Env:          []string{"BAR=bar", "FOO=foo"},
+				WorkingDir:   "/tmp",
+				Timeout:      time.Hour,
+				Retries:      2,
+				RetryBackoff: 30 * time.Second,
+				TestReport:   "junit.xml",
+			},
+			{
+				Name:      "test2",
+				Command:   "true",
+				Args:      []string{},
+				DependsOn: []string{},
+			},
+			{
+				Name:      "test3",
+				Command:   "true",
+				Args:      []string{},
+				DependsOn: []string{"test1", "test2"},
+			},
+			{
+				Name:    "cleanup",
+				Command: "true",
+				Args:    []string{},
+				Finally: true,
+			},
+		}))
+
+		By("parsing a JSON jobs file")
+		jobs, err = ParseJobsFile([]byte(`{"jobs": [{"name": "test1", "command": ["echo", "hello world"]}]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs).To(Equal([]Job{{Name: "test1", Command: "echo", Args: []string{"hello world"}}}))
+	})
+
+	It("should reject invalid jobs files", func() {
+		for _, data := range []string{
+			``,
+			`jobs: []`,
+			`jobs: [{name: "1st", command: ["true"]}]`,
+			`jobs: [{name: test1, command: []}]`,
+			`jobs: [{name: test1, command: ["true"], timeout: forever}]`,
+			`jobs: [{name: test1, command: ["true"], timeout: 0s}]`,
+			`jobs: [{name: test1, command: ["true"], retries: -1}]`,
+			`jobs: [{name: test1, command: ["true"], retryBackoff: 0s}]`,
+			`jobs: [{name: test1, command: ["true"], unknown: field}]`,
+		} {
+			_, err := ParseJobsFile([]byte(data))
+			Expect(err).To(HaveOccurred(), data)
+		}
+	})
+
+	It("should run a job with the environment variables and the working directory", func() {
+		cancel := startRunner([]Job{
+			{
+				Name:       "test1",
+				Command:    "sh",
+				Args:       []string{"-c", "echo $FOO; pwd"},
+				Env:        []string{"FOO=foo"},
+				WorkingDir: "/",
+			},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}},
+		}))
+		Expect(getLogs("test1", "")).To(Equal("foo\n/\n"))
+	})
+})
@@ -0,0 +1,128 @@
+package entrypoint
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLogLines is the maximum number of lines kept for each job.
+	maxLogLines = 10000
+
+	// maxLogBytes is the maximum total length of the lines kept for each job.
+	maxLogBytes = 4 * 1024 * 1024
+
+	// maxLogLineLength is the maximum length of a line.
+	// Longer lines are split into multiple lines.
+	maxLogLineLength = 64 * 1024
+)
+
+type logLine struct {
+	// Seq is the sequence number of the line in the buffer.
+	// It is zero for the last line not terminated by a newline yet.
+	Seq  uint64
+	Time time.Time
+	Text string
+}
+
+// logBuffer keeps the last lines written by a job.
+// The oldest lines are dropped when the number or the total length of the lines exceeds the limit.
+type logBuffer struct {
+	mu sync.Mutex
+	// lines is the lines kept in the buffer, from the oldest to the newest.
+	lines []logLine
+	size  int
+	seq   uint64
+
+	// onLine is called for each line appended to the buffer.
+	onLine func(logLine)
+
+	// partial is the last line which is not terminated by a newline yet.
+	partial     []byte
+	partialTime time.Time
+}
+
+func newLogBuffer(onLine func(logLine)) *logBuffer {
+	return &logBuffer{
+		lines:  make([]logLine, 0, 64),
+		onLine: onLine,
+	}
+}
+
+// Write implements io.Writer.
+func (b *logBuffer) Write(p []byte) (int, error) {
+	appended := b.write(p)
+	if b.onLine != nil {
+		for _, line := range appended {
+			b.onLine(line)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *logBuffer) write(p []byte) []logLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var appended []logLine
+	now := time.Now().UTC()
+	data := p
+	for len(data) > 0 {
+		if len(b.partial) == 0 {
+			b.partialTime = now
+		}
+		n := min(len(data), maxLogLineLength-len(b.partial))
+		i := bytes.IndexByte(data[:n], '\n')
+		if i < 0 {
+			b.partial = append(b.partial, data[:n]...)
+			data = data[n:]
+			if len(b.partial) == maxLogLineLength {
+				appended = append(appended, b.appendLine(b.partialTime, b.partial))
+				b.partial = b.partial[:0]
+			}
+			continue
+		}
+		b.partial = append(b.partial, data[:i]...)
+		appended = append(appended, b.appendLine(b.partialTime, b.partial))
+		b.partial = b.partial[:0]
+		data = data[i+1:]
+	}
+	return appended
+}
+
+func (b *logBuffer) appendLine(t time.Time, text []byte) logLine {
+	b.seq++
+	line := logLine{Seq: b.seq, Time: t, Text: string(text)}
+	b.lines = append(b.lines, line)
+	b.size += len(line.Text)
+	for len(b.lines) > maxLogLines || b.size > maxLogBytes {
+		b.size -= len(b.lines[0].Text)
+		b.lines[0] = logLine{}
+		b.lines = b.lines[1:]
+	}
+	return line
+}
+
+// Lines returns the lines written at or after since.
+// If tail is positive, only the last tail lines are returned.
+// An unterminated last line is also returned.
+func (b *logBuffer) Lines(since time.Time, tail int) []logLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := make([]logLine, 0, len(b.lines)+1)
+	for _, line := range b.lines {
+		if line.Time.Before(since) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(b.partial) > 0 && !b.partialTime.Before(since) {
+		lines = append(lines, logLine{Time: b.partialTime, Text: string(b.partial)})
+	}
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return lines
+}
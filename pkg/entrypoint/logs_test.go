@@ -0,0 +1,52 @@
+package entrypoint
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint log buffer test", func() {
+	It("should split an oversized chunk into lines of the maximum length", func() {
+		var appended []logLine
+		buf := newLogBuffer(func(line logLine) { appended = append(appended, line) })
+
+		_, err := buf.Write([]byte(strings.Repeat("a", 2*maxLogLineLength+10) + "\nb"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(appended).To(HaveLen(3))
+		Expect(appended[0].Text).To(HaveLen(maxLogLineLength))
+		Expect(appended[1].Text).To(HaveLen(maxLogLineLength))
+		Expect(appended[2].Text).To(Equal(strings.Repeat("a", 10)))
+
+		lines := buf.Lines(time.Time{}, 0)
+		Expect(lines).To(HaveLen(4))
+		Expect(lines[3].Text).To(Equal("b"))
+		Expect(lines[3].Seq).To(BeZero())
+
+		By("splitting the line continued from the unterminated line")
+		_, err = buf.Write([]byte(strings.Repeat("b", maxLogLineLength)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(appended).To(HaveLen(4))
+		Expect(appended[3].Text).To(Equal(strings.Repeat("b", maxLogLineLength)))
+		lines = buf.Lines(time.Time{}, 1)
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0].Text).To(Equal("b"))
+	})
+
+	It("should keep the lines up to the total length", func() {
+		buf := newLogBuffer(nil)
+		line := strings.Repeat("a", maxLogLineLength-1) + "\n"
+		count := maxLogBytes/(maxLogLineLength-1) + 10
+		for range count {
+			_, err := buf.Write([]byte(line))
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		lines := buf.Lines(time.Time{}, 0)
+		Expect(len(lines) * (maxLogLineLength - 1)).To(BeNumerically("<=", maxLogBytes))
+		Expect(lines[len(lines)-1].Seq).To(BeNumerically("==", count))
+		Expect(lines[0].Seq).To(BeNumerically("==", count-len(lines)+1))
+	})
+})
@@ -0,0 +1,147 @@
+package entrypoint
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const metricsNamespace = "nyamber_runner"
+
+// jobStatuses is the list of all job statuses.
+var jobStatuses = []string{
+	JobStatusPending,
+	JobStatusRunning,
+	JobStatusCompleted,
+	JobStatusFailed,
+	JobStatusTimedOut,
+	JobStatusSkipped,
+	JobStatusCancelled,
+	JobStatusInterrupted,
+	JobStatusTerminated,
+}
+
+var (
+	jobStatusDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_status"),
+		"The status of the job. The value is 1 for the current status and 0 for the others.",
+		[]string{"job", "status"}, nil,
+	)
+	jobStartTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_start_time_seconds"),
+		"The time when the job started in unix time.",
+		[]string{"job"}, nil,
+	)
+	jobEndTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_end_time_seconds"),
+		"The time when the job finished in unix time.",
+		[]string{"job"}, nil,
+	)
+	jobDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_duration_seconds"),
+		"The duration of the job. For a running job, this is the time elapsed since it started.",
+		[]string{"job"}, nil,
+	)
+	jobAttemptsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_attempts"),
+		"The number of attempts of the job including the current one.",
+		[]string{"job"}, nil,
+	)
+	jobCPUSecondsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_cpu_seconds_total"),
+		"The CPU time spent by the finished attempts of the job.",
+		[]string{"job", "mode"}, nil,
+	)
+	jobMaxRSSDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_max_rss_bytes"),
+		"The maximum resident set size of the finished attempts of the job.",
+		[]string{"job"}, nil,
+	)
+	jobReadBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_read_bytes_total"),
+		"The bytes read from the block devices by the finished attempts of the job.",
+		[]string{"job"}, nil,
+	)
+	jobWriteBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "job_write_bytes_total"),
+		"The bytes written to the block devices by the finished attempts of the job.",
+		[]string{"job"}, nil,
+	)
+	uptimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "uptime_seconds"),
+		"The time elapsed since the runner started.",
+		nil, nil,
+	)
+)
+
+// runnerCollector collects the metrics from the current job states.
+type runnerCollector struct {
+	runner *Runner
+}
+
+var _ prometheus.Collector = runnerCollector{}
+
+func (c runnerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobStatusDesc
+	ch <- jobStartTimeDesc
+	ch <- jobEndTimeDesc
+	ch <- jobDurationDesc
+	ch <- jobAttemptsDesc
+	ch <- jobCPUSecondsDesc
+	ch <- jobMaxRSSDesc
+	ch <- jobReadBytesDesc
+	ch <- jobWriteBytesDesc
+	ch <- uptimeDesc
+}
+
+func (c runnerCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	ch <- prometheus.MustNewConstMetric(uptimeDesc, prometheus.GaugeValue, now.Sub(c.runner.startTime).Seconds())
+
+	for _, state := range c.runner.getJobStates() {
+		for _, status := range jobStatuses {
+			value := 0.0
+			if state.Status == status {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(jobStatusDesc, prometheus.GaugeValue, value, state.Name, status)
+		}
+		ch <- prometheus.MustNewConstMetric(jobAttemptsDesc, prometheus.GaugeValue, float64(state.Attempt), state.Name)
+
+		if usage := state.ResourceUsage; usage != nil {
+			ch <- prometheus.MustNewConstMetric(jobCPUSecondsDesc, prometheus.CounterValue, usage.UserCPUSeconds, state.Name, "user")
+			ch <- prometheus.MustNewConstMetric(jobCPUSecondsDesc, prometheus.CounterValue, usage.SystemCPUSeconds, state.Name, "system")
+			ch <- prometheus.MustNewConstMetric(jobMaxRSSDesc, prometheus.GaugeValue, float64(usage.MaxRSSBytes), state.Name)
+			ch <- prometheus.MustNewConstMetric(jobReadBytesDesc, prometheus.CounterValue, float64(usage.ReadBytes), state.Name)
+			ch <- prometheus.MustNewConstMetric(jobWriteBytesDesc, prometheus.CounterValue, float64(usage.WriteBytes), state.Name)
+		}
+
+		startTime, err := time.Parse(time.RFC3339, state.StartTime)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(jobStartTimeDesc, prometheus.GaugeValue, float64(startTime.Unix()), state.Name)
+
+		endTime, err := time.Parse(time.RFC3339, state.EndTime)
+		if err != nil {
+			if state.Status == JobStatusRunning {
+				ch <- prometheus.MustNewConstMetric(jobDurationDesc, prometheus.GaugeValue, now.Sub(startTime).Seconds(), state.Name)
+			}
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(jobEndTimeDesc, prometheus.GaugeValue, float64(endTime.Unix()), state.Name)
+		ch <- prometheus.MustNewConstMetric(jobDurationDesc, prometheus.GaugeValue, endTime.Sub(startTime).Seconds(), state.Name)
+	}
+}
+
+// newMetricsRegistry returns a registry with the metrics of the runner and the process.
+func (r *Runner) newMetricsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		runnerCollector{runner: r},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
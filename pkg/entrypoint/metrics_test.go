@@ -0,0 +1,68 @@
+package entrypoint
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint metrics test", func() {
+	It("should expose the metrics of jobs", func() {
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+			{Name: "test2", Command: "sleep", Args: []string{"30"}},
+			{Name: "test3", Command: "true", Args: []string{}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Running"}, {Name: "test3", Status: "Pending"}},
+		}))
+
+		metrics, err := getMetrics()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_status{job="test1",status="Completed"} 1`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_status{job="test1",status="Running"} 0`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_status{job="test2",status="Running"} 1`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_status{job="test3",status="Pending"} 1`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_attempts{job="test1"} 1`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_attempts{job="test3"} 0`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_start_time_seconds{job="test1"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_end_time_seconds{job="test1"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_duration_seconds{job="test1"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_duration_seconds{job="test2"}`))
+		Expect(metrics).NotTo(ContainSubstring(`nyamber_runner_job_end_time_seconds{job="test2"}`))
+		Expect(metrics).NotTo(ContainSubstring(`nyamber_runner_job_start_time_seconds{job="test3"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_cpu_seconds_total{job="test1",mode="user"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_cpu_seconds_total{job="test1",mode="system"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_max_rss_bytes{job="test1"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_read_bytes_total{job="test1"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_job_write_bytes_total{job="test1"}`))
+		Expect(metrics).NotTo(ContainSubstring(`nyamber_runner_job_max_rss_bytes{job="test2"}`))
+		Expect(metrics).To(ContainSubstring(`nyamber_runner_uptime_seconds`))
+		Expect(metrics).To(ContainSubstring(`go_goroutines`))
+	})
+})
+
+func getMetrics() (string, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.MetricsEndPoint))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
@@ -0,0 +1,90 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// notifyInterval is the minimum interval of the notifications.
+	// The changes of the job states in the interval are sent as one notification.
+	notifyInterval = time.Second
+	// notifyMaxRetryInterval is the maximum interval of the retries of a failed notification.
+	notifyMaxRetryInterval = 30 * time.Second
+
+	notifyTimeout = 10 * time.Second
+)
+
+// notify requests to notify NotifyURL of a change of the job states.
+// It does not block, and the requests made while a notification is pending are merged.
+func (r *Runner) notify() {
+	if r.options.NotifyURL == "" {
+		return
+	}
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// runNotifier notifies NotifyURL of the changes of the job states until ctx is done.
+// The receiver may miss a notification, e.g. when the request reaches a replica of the controller
+// which is not the leader, so a failed notification is retried with exponential backoff
+// until it succeeds or another change is notified successfully.
+func (r *Runner) runNotifier(ctx context.Context) error {
+	retryInterval := notifyInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.changed:
+		}
+
+		interval := notifyInterval
+		if err := r.sendNotification(ctx); err != nil {
+			r.logger.Error(err, "failed to notify the change of the job states", "notify_url", r.options.NotifyURL, "retry_after", retryInterval.String())
+			r.notify()
+			interval = retryInterval
+			retryInterval = min(retryInterval*2, notifyMaxRetryInterval)
+		} else {
+			retryInterval = notifyInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (r *Runner) sendNotification(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.options.NotifyURL, nil)
+	if err != nil {
+		return err
+	}
+	if r.options.TokenFile != "" {
+		token, err := readToken(r.options.TokenFile)
+		if err != nil {
+			return err
+		}
+		setToken(req, token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package entrypoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type notifyReceiver struct {
+	mu     sync.Mutex
+	tokens []string
+	// misses is the number of the notifications to reject before accepting them.
+	misses   int
+	accepted int
+}
+
+func (n *notifyReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.tokens = append(n.tokens, req.Header.Get("Authorization"))
+	if len(n.tokens) <= n.misses {
+		http.Error(w, "not watched", http.StatusNotFound)
+		return
+	}
+	n.accepted++
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (n *notifyReceiver) acceptedCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.accepted
+}
+
+func (n *notifyReceiver) received() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.tokens...)
+}
+
+var _ = Describe("entrypoint notify test", func() {
+	It("should notify the changes of the job states", func() {
+		receiver := &notifyReceiver{}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+			{Name: "test2", Command: "sleep", Args: []string{"2"}},
+		}, RunnerOptions{NotifyURL: server.URL})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.1).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Running"}},
+		}))
+
+		By("merging the changes in a short time into a few notifications")
+		Eventually(receiver.received, 10, 0.1).ShouldNot(BeEmpty())
+		count := len(receiver.received())
+		Expect(count).To(BeNumerically("<=", 2))
+		Expect(receiver.received()[0]).To(BeEmpty())
+
+		By("notifying the completion of the job")
+		Eventually(getStatus, 10, 0.1).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Completed"}},
+		}))
+		Eventually(func() int { return len(receiver.received()) }, 10, 0.1).Should(BeNumerically(">", count))
+	})
+
+	It("should send the token with the notifications", func() {
+		receiver := &notifyReceiver{}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("secret\n"), 0600)).To(Succeed())
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+		}, RunnerOptions{TokenFile: tokenFile, NotifyURL: server.URL})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+
+		Eventually(receiver.received, 10, 0.1).ShouldNot(BeEmpty())
+		Expect(receiver.received()).To(HaveEach("Bearer secret"))
+	})
+
+	It("should retry the missed notifications", func() {
+		receiver := &notifyReceiver{misses: 2}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+		}, RunnerOptions{NotifyURL: server.URL})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.1).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}},
+		}))
+
+		By("retrying the notification without further changes")
+		Eventually(receiver.acceptedCount, 10, 0.1).Should(Equal(1))
+		Expect(len(receiver.received())).To(Equal(3))
+		Consistently(receiver.acceptedCount, 2, 0.1).Should(Equal(1))
+	})
+})
@@ -0,0 +1,82 @@
+package entrypoint
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// OutputEnvName is the name of the environment variable which has the path of the file to write outputs of a job.
+	// A job writes each output as a KEY=VALUE line to the file.
+	OutputEnvName = "NYAMBER_OUTPUT"
+
+	// outputEnvPrefix is the prefix of the environment variables which pass the outputs of other jobs.
+	outputEnvPrefix = "NYAMBER_OUTPUT_"
+
+	// maxOutputsSize is the maximum size of an outputs file to read.
+	maxOutputsSize = 1 << 20
+)
+
+var reOutputKey = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// outputEnvName returns the name of the environment variable for the output of the job,
+// e.g. NYAMBER_OUTPUT_NECO_BOOTSTRAP_COMMIT for the output "commit" of the job "neco_bootstrap".
+func outputEnvName(jobName, key string) string {
+	return outputEnvPrefix + strings.ToUpper(strings.ReplaceAll(jobName+"_"+key, "-", "_"))
+}
+
+// outputsEnv returns the environment variables which pass the outputs of the jobs other than the i-th job.
+func (r *Runner) outputsEnv(i int) []string {
+	var env []string
+	for j, state := range r.getJobStates() {
+		if j == i {
+			continue
+		}
+		keys := make([]string, 0, len(state.Outputs))
+		for k := range state.Outputs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			env = append(env, outputEnvName(state.Name, k)+"="+state.Outputs[k])
+		}
+	}
+	return env
+}
+
+// readOutputs reads the outputs written as KEY=VALUE lines.
+// Empty lines are ignored. It returns nil if there are no outputs.
+func readOutputs(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var outputs map[string]string
+	scanner := bufio.NewScanner(io.LimitReader(f, maxOutputsSize))
+	scanner.Buffer(nil, maxOutputsSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !reOutputKey.MatchString(key) {
+			return nil, fmt.Errorf("output must be formatted as KEY=VALUE: %q", line)
+		}
+		if outputs == nil {
+			outputs = make(map[string]string)
+		}
+		outputs[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
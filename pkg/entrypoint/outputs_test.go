@@ -0,0 +1,46 @@
+package entrypoint
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint outputs test", func() {
+	It("should pass the outputs of jobs to later jobs", func() {
+		cancel := startRunner([]Job{
+			{
+				Name:    "test-1",
+				Command: "sh",
+				Args:    []string{"-c", `echo commit=abc123 >> $NYAMBER_OUTPUT; echo >> $NYAMBER_OUTPUT; echo "ips=10.0.0.1 10.0.0.2" >> $NYAMBER_OUTPUT`},
+			},
+			{
+				Name:    "test2",
+				Command: "sh",
+				Args:    []string{"-c", `echo "$NYAMBER_OUTPUT_TEST_1_COMMIT/$NYAMBER_OUTPUT_TEST_1_IPS"`},
+			},
+			{
+				Name:      "test3",
+				Command:   "sh",
+				Args:      []string{"-c", `echo "bad output" >> $NYAMBER_OUTPUT`},
+				DependsOn: []string{},
+			},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test-1", Status: "Completed"}, {Name: "test2", Status: "Completed"}, {Name: "test3", Status: "Failed"}},
+		}))
+
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Outputs).To(Equal(map[string]string{
+			"commit": "abc123",
+			"ips":    "10.0.0.1 10.0.0.2",
+		}))
+		Expect(resp.Jobs[1].Outputs).To(BeNil())
+		Expect(resp.Jobs[2].Message).To(HavePrefix("invalid outputs: "))
+		Expect(getLogs("test2", "")).To(Equal("abc123/10.0.0.1 10.0.0.2\n"))
+	})
+})
@@ -0,0 +1,127 @@
+package entrypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+)
+
+const (
+	// JobNameEnvName is the name of the environment variable which has the name of the running job.
+	JobNameEnvName = "NYAMBER_JOB_NAME"
+
+	// RunnerURLEnvName is the name of the environment variable which has the URL of the runner API.
+	RunnerURLEnvName = "NYAMBER_RUNNER_URL"
+)
+
+// Progress is the progress of a job reported by the job itself.
+type Progress struct {
+	// Step is the name of the current step of the job.
+	Step string `json:"step"`
+
+	// Percent is the progress of the job in percent. This is optional.
+	Percent *int `json:"percent,omitempty"`
+}
+
+func (p *Progress) validate() error {
+	if p.Step == "" {
+		return errors.New("step must not be empty")
+	}
+	if p.Percent != nil && (*p.Percent < 0 || *p.Percent > 100) {
+		return errors.New("percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// runnerURL returns the URL for the jobs to access the runner API.
+func (r *Runner) runnerURL() string {
+	host, port, err := net.SplitHostPort(r.listenAddr)
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// reportProgress updates the progress of the i-th job. The job must be running.
+func (r *Runner) reportProgress(i int, progress Progress) error {
+	updated := r.updateJobStateIf(i, JobStatusRunning, func(state *JobState) {
+		state.Step = progress.Step
+		state.Percent = progress.Percent
+	})
+	if !updated {
+		return fmt.Errorf("job %s is not running", r.jobs[i].Name)
+	}
+	return nil
+}
+
+func (r *Runner) progressHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobName := strings.TrimPrefix(req.URL.Path, "/"+constants.ProgressEndPoint+"/")
+	i, ok := r.jobIndex(jobName)
+	if !ok {
+		http.Error(w, fmt.Sprintf("job %q is not found", jobName), http.StatusNotFound)
+		return
+	}
+
+	progress := Progress{}
+	if err := json.NewDecoder(req.Body).Decode(&progress); err != nil {
+		http.Error(w, fmt.Sprintf("invalid progress: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := progress.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := r.reportProgress(i, progress); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReportProgress reports the progress of the job to the runner at runnerURL.
+// If token is not empty, it is sent to the runner for authentication.
+func ReportProgress(runnerURL, token, jobName string, progress Progress) error {
+	if err := progress.validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	u, err := url.JoinPath(runnerURL, constants.ProgressEndPoint, jobName)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setToken(req, token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to report progress: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package entrypoint
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("entrypoint progress test", func() {
+	It("should update the progress of a running job", func() {
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "sh", Args: []string{"-c", `echo "$NYAMBER_JOB_NAME $NYAMBER_RUNNER_URL"; sleep 30`}},
+			{Name: "test2", Command: "true", Args: []string{}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Running"}, {Name: "test2", Status: "Pending"}},
+		}))
+		Eventually(func() (string, error) { return getLogs("test1", "") }, 10, 0.5).
+			Should(Equal(fmt.Sprintf("test1 http://localhost:%d\n", constants.ListenPort)))
+
+		runnerURL := "http://" + net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort))
+		Expect(ReportProgress(runnerURL, "", "test1", Progress{Step: "argocd sync", Percent: ptr.To(40)})).To(Succeed())
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Step).To(Equal("argocd sync"))
+		Expect(resp.Jobs[0].Percent).To(HaveValue(Equal(40)))
+
+		Expect(ReportProgress(runnerURL, "", "test1", Progress{Step: "wait"})).To(Succeed())
+		resp, err = getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Step).To(Equal("wait"))
+		Expect(resp.Jobs[0].Percent).To(BeNil())
+
+		By("reporting invalid progress")
+		Expect(ReportProgress(runnerURL, "", "test1", Progress{})).NotTo(Succeed())
+		Expect(ReportProgress(runnerURL, "", "test1", Progress{Step: "wait", Percent: ptr.To(101)})).NotTo(Succeed())
+		Expect(ReportProgress(runnerURL, "", "test2", Progress{Step: "wait"})).NotTo(Succeed())
+		Expect(ReportProgress(runnerURL, "", "unknown", Progress{Step: "wait"})).NotTo(Succeed())
+	})
+})
@@ -3,14 +3,21 @@ package entrypoint
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cybozu-go/nyamber/pkg/constants"
 	"github.com/cybozu-go/well"
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type StatusResponse struct {
@@ -22,50 +29,265 @@ type JobState struct {
 	Status    string `json:"status"`
 	StartTime string `json:"startTime,omitempty"`
 	EndTime   string `json:"endTime,omitempty"`
+
+	// Finally is true if the job is a finally job.
+	Finally bool `json:"finally,omitempty"`
+
+	// Outputs is the key/value outputs written by the last attempt of the job.
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// Step and Percent are the progress reported by the job.
+	Step    string `json:"step,omitempty"`
+	Percent *int   `json:"percent,omitempty"`
+
+	// TestReport is the summary of the test report written by the last attempt of the job.
+	TestReport *TestReport `json:"testReport,omitempty"`
+
+	// ResourceUsage is the resource usage of all the finished attempts of the job.
+	ResourceUsage *ResourceUsage `json:"resourceUsage,omitempty"`
+
+	// ExitCode, Signal and Message are the result of the last attempt.
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Signal   string `json:"signal,omitempty"`
+	Message  string `json:"message,omitempty"`
+
+	// Attempt is the number of the current or the last attempt, starting from 1.
+	Attempt  int             `json:"attempt,omitempty"`
+	Attempts []AttemptResult `json:"attempts,omitempty"`
+}
+
+// AttemptResult is the result of an attempt to run a job.
+type AttemptResult struct {
+	Attempt   int    `json:"attempt"`
+	Status    string `json:"status"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+
+	// ExitCode is the exit code of the command. This is nil if the command was killed by a signal or did not start.
+	ExitCode *int `json:"exitCode,omitempty"`
+
+	// Signal is the name of the signal which terminated the command, e.g. "SIGKILL".
+	Signal string `json:"signal,omitempty"`
+
+	// Message describes why the attempt did not complete.
+	Message string `json:"message,omitempty"`
+
+	// ResourceUsage is the resource usage of the attempt. This is nil if the command did not start.
+	ResourceUsage *ResourceUsage `json:"resourceUsage,omitempty"`
 }
 
 const (
-	JobStatusPending   = "Pending"
-	JobStatusRunning   = "Running"
-	JobStatusCompleted = "Completed"
-	JobStatusFailed    = "Failed"
+	JobStatusPending     = "Pending"
+	JobStatusRunning     = "Running"
+	JobStatusCompleted   = "Completed"
+	JobStatusFailed      = "Failed"
+	JobStatusTimedOut    = "TimedOut"
+	JobStatusSkipped     = "Skipped"
+	JobStatusCancelled   = "Cancelled"
+	JobStatusInterrupted = "Interrupted"
+
+	// JobStatusTerminated is the status of a job cancelled by the shutdown of the runner.
+	// Unlike Cancelled, the job runs again according to ResumePolicy when the runner restarts with the recorded states.
+	JobStatusTerminated = "Terminated"
 )
 
 type Job struct {
 	Name    string
 	Command string
 	Args    []string
+
+	// Env is the environment variables in KEY=VALUE form added to the job.
+	Env []string
+
+	// WorkingDir is the working directory of the job.
+	// If this is empty, the job runs in the working directory of the entrypoint.
+	WorkingDir string
+
+	// Timeout is the maximum duration of the job. Zero means no timeout.
+	Timeout time.Duration
+
+	// Retries is the number of times to retry the job when it fails or times out.
+	Retries int
+
+	// RetryBackoff is the duration to wait before the first retry.
+	// The duration doubles for every retry. Zero means DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// DependsOn is the names of the jobs which must complete before this job starts.
+	// If this is nil, the job depends on the previous job of the same kind.
+	// If this is empty but not nil, the job starts immediately.
+	DependsOn []string
+
+	// Finally makes the job run after all the other jobs have finished, whether they have succeeded or not.
+	// A finally job waits for its dependencies to finish instead of to complete, and is never skipped.
+	// Other jobs cannot depend on a finally job.
+	Finally bool
+
+	// TestReport is the path of a JUnit XML or Ginkgo JSON report written by the job.
+	// A relative path is relative to WorkingDir.
+	// The report is parsed after each attempt, and its summary appears in the state of the job.
+	TestReport string
+}
+
+// EnvNamePrefix is the prefix of the environment variables which the runner sets to the jobs,
+// e.g. NYAMBER_TOKEN and NYAMBER_OUTPUT_<JOB>_<KEY>.
+const EnvNamePrefix = "NYAMBER_"
+
+const (
+	DefaultRetryBackoff = 10 * time.Second
+	maxRetryBackoff     = 10 * time.Minute
+
+	// DefaultGracePeriod is the default time to wait for a job to exit after SIGTERM before killing it.
+	DefaultGracePeriod = 30 * time.Second
+)
+
+// RunnerOptions is the optional configuration of Runner.
+type RunnerOptions struct {
+	// StateDir is the directory to record the job states.
+	// If this is set, the runner restores the job states from the directory when it starts.
+	// If this is empty, the job states are not recorded.
+	StateDir string
+
+	// ResumePolicy is the policy to handle the jobs which were running when the runner restarted.
+	// Empty means ResumePolicyResume.
+	ResumePolicy string
+
+	// ReadyJob is the name of the job which must complete before the runner reports ready.
+	// If this is empty, the runner is ready once its HTTP server is up.
+	ReadyJob string
+
+	// GracePeriod is the time to wait for a job to exit after SIGTERM before killing it with SIGKILL.
+	// Zero means DefaultGracePeriod.
+	GracePeriod time.Duration
+
+	// TokenFile is the file which has the token to access the API.
+	// If this is set, the API other than the health checks and the metrics requires the token.
+	TokenFile string
+
+	// Terminal enables the terminal API. This requires TokenFile.
+	Terminal bool
+
+	// TerminalShell is the shell run by the terminal API. Empty means DefaultTerminalShell.
+	TerminalShell string
+
+	// NotifyURL is the URL to be notified of the changes of the job states by a POST request.
+	// The token in TokenFile is sent with the request if TokenFile is set.
+	// If this is empty, the runner does not notify anyone, and clients need to poll the status API.
+	NotifyURL string
+}
+
+func (o *RunnerOptions) validate(jobs []Job) error {
+	switch o.ResumePolicy {
+	case "", ResumePolicyResume, ResumePolicyInterrupt:
+	default:
+		return fmt.Errorf("unknown resume policy %q", o.ResumePolicy)
+	}
+	if o.ReadyJob != "" && !slices.ContainsFunc(jobs, func(job Job) bool { return job.Name == o.ReadyJob }) {
+		return fmt.Errorf("unknown ready job %s", o.ReadyJob)
+	}
+	if o.GracePeriod < 0 {
+		return errors.New("grace period must not be negative")
+	}
+	if o.Terminal && o.TokenFile == "" {
+		return errors.New("terminal requires a token file")
+	}
+	return nil
+}
+
+func (o *RunnerOptions) gracePeriod() time.Duration {
+	if o.GracePeriod == 0 {
+		return DefaultGracePeriod
+	}
+	return o.GracePeriod
+}
+
+func (o *RunnerOptions) terminalShell() string {
+	if o.TerminalShell == "" {
+		return DefaultTerminalShell
+	}
+	return o.TerminalShell
 }
 
 type Runner struct {
 	listenAddr string
 	logger     logr.Logger
 	jobs       []Job
+	deps       [][]int
+	options    RunnerOptions
+	startTime  time.Time
 
 	mutex     sync.Mutex
 	jobStates []JobState
+	cancels   []context.CancelCauseFunc
+	logs      map[string]*logBuffer
+	events    *broker
+
+	// wake is notified when jobs are reset to be rerun.
+	wake chan struct{}
+
+	// changed is notified when the job states change. See notify.
+	changed chan struct{}
+
+	// stateVersion is the version of the job states taken by snapshotState. This is protected by mutex.
+	stateVersion uint64
+	// stateFileMutex serializes the writes of the state file, and protects savedVersion.
+	stateFileMutex sync.Mutex
+	savedVersion   uint64
 }
 
-func NewRunner(listenAddr string, logger logr.Logger, jobs []Job) *Runner {
+func NewRunner(listenAddr string, logger logr.Logger, jobs []Job, options RunnerOptions) (*Runner, error) {
+	if err := options.validate(jobs); err != nil {
+		return nil, err
+	}
+	deps, err := resolveDependencies(jobs)
+	if err != nil {
+		return nil, err
+	}
+
 	runner := &Runner{
 		listenAddr: listenAddr,
 		logger:     logger,
 		jobs:       jobs,
+		deps:       deps,
+		options:    options,
+		startTime:  time.Now(),
 		jobStates:  make([]JobState, len(jobs)),
+		cancels:    make([]context.CancelCauseFunc, len(jobs)),
+		logs:       make(map[string]*logBuffer),
+		events:     newBroker(),
+		wake:       make(chan struct{}, 1),
+		changed:    make(chan struct{}, 1),
 	}
 	for i, job := range jobs {
-		runner.jobStates[i].Name = job.Name
-		runner.jobStates[i].Status = JobStatusPending
+		runner.jobStates[i] = initialJobState(job)
+		runner.logs[job.Name] = newLogBuffer(func(line logLine) {
+			runner.publishLog(job.Name, line)
+		})
+	}
+	if err := runner.restoreState(); err != nil {
+		return nil, err
 	}
-	return runner
+	return runner, nil
 }
 
 func (r *Runner) Run(ctx context.Context) error {
 	env := well.NewEnvironment(ctx)
 	env.Go(r.runJobs)
+	if r.options.NotifyURL != "" {
+		env.Go(r.runNotifier)
+	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/"+constants.StatusEndPoint, http.HandlerFunc(r.statusHandler))
+	mux.Handle("/"+constants.StatusEndPoint, r.authenticate(r.statusHandler))
+	mux.Handle("/"+constants.LogsEndPoint+"/", r.authenticate(r.logsHandler))
+	mux.Handle("/"+constants.EventsEndPoint, r.authenticate(r.eventsHandler))
+	mux.Handle("/"+constants.CancelEndPoint+"/", r.authenticate(r.cancelHandler))
+	mux.Handle("/"+constants.RerunEndPoint+"/", r.authenticate(r.rerunHandler))
+	mux.Handle("/"+constants.ProgressEndPoint+"/", r.authenticate(r.progressHandler))
+	mux.Handle("/"+constants.TerminalEndPoint, r.authenticate(r.terminalHandler))
+	mux.Handle("/"+constants.HealthzEndPoint, http.HandlerFunc(r.healthzHandler))
+	mux.Handle("/"+constants.ReadyzEndPoint, http.HandlerFunc(r.readyzHandler))
+	mux.Handle("/"+constants.MetricsEndPoint, promhttp.HandlerFor(r.newMetricsRegistry(), promhttp.HandlerOpts{}))
 	serv := &well.HTTPServer{
 		Env: env,
 		Server: &http.Server{
@@ -82,36 +304,343 @@ func (r *Runner) Run(ctx context.Context) error {
 	return env.Wait()
 }
 
+// runJobs runs each job after all of its dependencies have completed.
+// Jobs whose dependencies are satisfied run in parallel.
+// A job is skipped if any of its dependencies did not complete successfully.
+// After all jobs have finished, it waits for jobs to be rerun until ctx is done.
+// When ctx is done, it waits for the running jobs to be terminated and runs the finally jobs before returning.
 func (r *Runner) runJobs(ctx context.Context) error {
+	done := make(chan struct{})
+	running := 0
+	for {
+		running += r.startReadyJobs(ctx, done)
+		select {
+		case <-done:
+			running--
+		case <-r.wake:
+		case <-ctx.Done():
+			if running == 0 {
+				r.shutdown(ctx, done)
+				return nil
+			}
+			<-done
+			running--
+		}
+	}
+}
+
+// shutdown terminates the pending jobs other than finally jobs, and runs the finally jobs which have not run yet.
+func (r *Runner) shutdown(ctx context.Context, done chan struct{}) {
 	for i, job := range r.jobs {
-		r.logger.Info("execute job", "job_name", job.Name)
-		startTime := time.Now().UTC().Format(time.RFC3339)
-		r.mutex.Lock()
-		r.jobStates[i].StartTime = startTime
-		r.jobStates[i].Status = JobStatusRunning
-		r.mutex.Unlock()
+		if job.Finally {
+			continue
+		}
+		r.updateJobStateIf(i, JobStatusPending, func(state *JobState) {
+			state.Status = JobStatusTerminated
+			state.Message = shutdownMessage
+		})
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	running := 0
+	for {
+		running += r.startReadyJobs(ctx, done)
+		if running == 0 {
+			return
+		}
+		<-done
+		running--
+	}
+}
+
+// startReadyJobs starts the pending jobs whose dependencies have completed,
+// and skips the pending jobs whose dependencies did not complete.
+// It returns the number of the started jobs. Each started job notifies done when it finishes.
+func (r *Runner) startReadyJobs(ctx context.Context, done chan<- struct{}) int {
+	started := 0
+	for progress := true; progress; {
+		progress = false
+		states := r.getJobStates()
+		for i, job := range r.jobs {
+			if states[i].Status != JobStatusPending {
+				continue
+			}
+			ready, skipReason := r.checkDependencies(i, states)
+			switch {
+			// After ctx is done, the jobs are left Pending so that shutdown marks them Terminated
+			// and they run again after a restart of the runner.
+			case skipReason != "" && ctx.Err() == nil:
+				skipped := r.updateJobStateIf(i, JobStatusPending, func(state *JobState) {
+					state.Status = JobStatusSkipped
+					state.Message = skipReason
+				})
+				if skipped {
+					r.logger.Info("skip job", "job_name", job.Name, "reason", skipReason)
+					progress = true
+				}
+			case ready && ctx.Err() == nil:
+				jobCtx, cancel := context.WithCancelCause(ctx)
+				startTime := timestamp()
+				ok := r.updateJobStateIf(i, JobStatusPending, func(state *JobState) {
+					state.StartTime = startTime
+					state.Status = JobStatusRunning
+					r.cancels[i] = cancel
+				})
+				if !ok {
+					cancel(nil)
+					continue
+				}
+				started++
+				go func() {
+					defer cancel(nil)
+					r.runJob(jobCtx, i, job)
+					done <- struct{}{}
+				}()
+			}
+		}
+	}
+	return started
+}
+
+// checkDependencies returns whether the i-th job is ready to run.
+// If the job should be skipped, it returns a non-empty reason.
+func (r *Runner) checkDependencies(i int, states []JobState) (ready bool, skipReason string) {
+	if r.jobs[i].Finally {
+		for j, state := range states {
+			if (!r.jobs[j].Finally || slices.Contains(r.deps[i], j)) && !isFinished(state.Status) {
+				return false, ""
+			}
+		}
+		return true, ""
+	}
 
-		cmd := well.CommandContext(ctx, job.Command, job.Args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		endTime := time.Now().UTC().Format(time.RFC3339)
+	ready = true
+	for _, j := range r.deps[i] {
+		switch states[j].Status {
+		case JobStatusCompleted:
+		case JobStatusPending, JobStatusRunning:
+			ready = false
+		default:
+			return false, fmt.Sprintf("dependency %s is %s", states[j].Name, states[j].Status)
+		}
+	}
+	return ready, ""
+}
+
+// runJob runs the i-th job until it succeeds, runs out of retries or is cancelled.
+func (r *Runner) runJob(ctx context.Context, i int, job Job) {
+	r.logger.Info("execute job", "job_name", job.Name)
+	job.Env = append(r.outputsEnv(i), job.Env...)
+	job.Env = append(job.Env, JobNameEnvName+"="+job.Name, RunnerURLEnvName+"="+r.runnerURL())
+	if r.options.TokenFile != "" {
+		token, err := readToken(r.options.TokenFile)
+		if err != nil {
+			r.logger.Error(err, "failed to read token for job", "job_name", job.Name, "token_file", r.options.TokenFile)
+		} else {
+			job.Env = append(job.Env, TokenEnvName+"="+token)
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		r.updateJobState(i, func(state *JobState) {
+			state.Attempt = attempt
+			// The progress reported by the previous attempt does not apply to this attempt.
+			state.Step = ""
+			state.Percent = nil
+		})
+		startTime := time.Now()
+		result, outputs := r.runAttempt(ctx, job, attempt)
+		report, err := readTestReport(job, startTime)
 		if err != nil {
-			r.logger.Error(err, "job execution error", "job_name", job.Name)
-			r.mutex.Lock()
-			r.jobStates[i].EndTime = endTime
-			r.jobStates[i].Status = JobStatusFailed
-			r.mutex.Unlock()
-			return nil
+			r.logger.Error(err, "failed to read test report", "job_name", job.Name, "attempt", attempt, "path", job.TestReport)
 		}
+		r.updateJobState(i, func(state *JobState) {
+			state.Attempts = append(state.Attempts, result)
+			state.Outputs = outputs
+			state.TestReport = report
+			state.ResourceUsage = addResourceUsage(state.ResourceUsage, result.ResourceUsage)
+		})
+
+		if result.Status == JobStatusCompleted || attempt > job.Retries || ctx.Err() != nil {
+			r.finishJob(i, result.EndTime, result)
+			return
+		}
+
+		backoff := retryBackoff(job, attempt)
+		r.logger.Info("retry job", "job_name", job.Name, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			result.Status = JobStatusTerminated
+			result.Message = shutdownMessage
+			if isCancelled(ctx) {
+				result.Status = JobStatusCancelled
+				result.Message = cancelledMessage
+			}
+			r.finishJob(i, timestamp(), result)
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// runAttempt runs the job once, and returns the result and the outputs written by the job.
+func (r *Runner) runAttempt(ctx context.Context, job Job, attempt int) (AttemptResult, map[string]string) {
+	result := AttemptResult{
+		Attempt:   attempt,
+		StartTime: timestamp(),
+	}
+
+	outputFile, err := os.CreateTemp("", "nyamber-output-")
+	if err != nil {
+		r.logger.Error(err, "failed to create outputs file", "job_name", job.Name, "attempt", attempt)
+		result.EndTime = timestamp()
+		result.Status = JobStatusFailed
+		result.Message = fmt.Sprintf("failed to create outputs file: %v", err)
+		return result, nil
+	}
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+	job.Env = append(job.Env, OutputEnvName+"="+outputFile.Name())
+
+	var jobCtx context.Context
+	var cancel context.CancelFunc
+	if job.Timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+	} else {
+		jobCtx, cancel = context.WithCancel(ctx)
+	}
+	cmd := newJobCommand(jobCtx, job, io.MultiWriter(os.Stdout, r.logs[job.Name]), r.options.gracePeriod())
+	err = cmd.Run()
+	timedOut := errors.Is(jobCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	cancel()
+	result.EndTime = timestamp()
+	result.ExitCode, result.Signal = exitStatus(cmd.ProcessState)
+	result.ResourceUsage = resourceUsage(cmd.ProcessState)
+	outputs, outputsErr := readOutputs(outputFile.Name())
+
+	switch {
+	case isCancelled(ctx):
+		r.logger.Info("job cancelled", "job_name", job.Name, "attempt", attempt)
+		result.Status = JobStatusCancelled
+		result.Message = cancelledMessage
+	case ctx.Err() != nil:
+		r.logger.Info("job terminated by shutdown", "job_name", job.Name, "attempt", attempt)
+		result.Status = JobStatusTerminated
+		result.Message = shutdownMessage
+	case timedOut:
+		r.logger.Error(err, "job timed out", "job_name", job.Name, "attempt", attempt, "timeout", job.Timeout)
+		result.Status = JobStatusTimedOut
+		result.Message = fmt.Sprintf("timed out after %s", job.Timeout)
+	case err != nil:
+		r.logger.Error(err, "job execution error", "job_name", job.Name, "attempt", attempt)
+		result.Status = JobStatusFailed
+		result.Message = failureMessage(err, result.ExitCode, result.Signal)
+	case outputsErr != nil:
+		r.logger.Error(outputsErr, "job wrote invalid outputs", "job_name", job.Name, "attempt", attempt)
+		result.Status = JobStatusFailed
+		result.Message = fmt.Sprintf("invalid outputs: %v", outputsErr)
+	default:
+		r.logger.Info("job completed", "job_name", job.Name, "attempt", attempt)
+		result.Status = JobStatusCompleted
+	}
+	return result, outputs
+}
+
+// finishJob sets the result of the last attempt to the state of the i-th job.
+func (r *Runner) finishJob(i int, endTime string, result AttemptResult) {
+	r.updateJobState(i, func(state *JobState) {
+		state.EndTime = endTime
+		state.Status = result.Status
+		state.ExitCode = result.ExitCode
+		state.Signal = result.Signal
+		state.Message = result.Message
+		r.cancels[i] = nil
+	})
+}
+
+// retryBackoff returns the duration to wait before retrying the job after the given attempt.
+// The duration doubles for every attempt.
+func retryBackoff(job Job, attempt int) time.Duration {
+	backoff := job.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// initialJobState returns the state of the job before it runs.
+func initialJobState(job Job) JobState {
+	return JobState{
+		Name:    job.Name,
+		Status:  JobStatusPending,
+		Finally: job.Finally,
+	}
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// updateJobState updates the state of the i-th job and notifies the subscribers of the change.
+func (r *Runner) updateJobState(i int, update func(state *JobState)) {
+	r.mutex.Lock()
+	before := r.jobStates[i]
+	update(&r.jobStates[i])
+	state := r.jobStates[i]
+	snapshot := r.snapshotStateIfTransited(before, state)
+	r.mutex.Unlock()
+	r.recordState(snapshot)
+
+	r.events.publish(event{
+		name: eventStatus,
+		job:  state.Name,
+		data: state,
+	})
+	r.notify()
+}
 
-		r.logger.Info("job completed", "job_name", job.Name)
-		r.mutex.Lock()
-		r.jobStates[i].EndTime = endTime
-		r.jobStates[i].Status = JobStatusCompleted
+// updateJobStateIf updates the state of the i-th job only if the job is in the given status.
+// It returns true if the state is updated.
+func (r *Runner) updateJobStateIf(i int, status string, update func(state *JobState)) bool {
+	r.mutex.Lock()
+	if r.jobStates[i].Status != status {
 		r.mutex.Unlock()
+		return false
 	}
-	return nil
+	before := r.jobStates[i]
+	update(&r.jobStates[i])
+	state := r.jobStates[i]
+	snapshot := r.snapshotStateIfTransited(before, state)
+	r.mutex.Unlock()
+	r.recordState(snapshot)
+
+	r.events.publish(event{
+		name: eventStatus,
+		job:  state.Name,
+		data: state,
+	})
+	r.notify()
+	return true
+}
+
+func (r *Runner) getJobStates() []JobState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	states := make([]JobState, len(r.jobStates))
+	copy(states, r.jobStates)
+	return states
+}
+
+// isFinished returns true if a job in the status will not change its status anymore unless it is rerun.
+func isFinished(status string) bool {
+	switch status {
+	case JobStatusCompleted, JobStatusFailed, JobStatusTimedOut, JobStatusSkipped, JobStatusCancelled, JobStatusInterrupted, JobStatusTerminated:
+		return true
+	}
+	return false
 }
 
 func (r *Runner) statusHandler(w http.ResponseWriter, req *http.Request) {
@@ -131,3 +660,63 @@ func (r *Runner) statusHandler(w http.ResponseWriter, req *http.Request) {
 	}
 	w.Write(data)
 }
+
+func (r *Runner) logsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobName := strings.TrimPrefix(req.URL.Path, "/"+constants.LogsEndPoint+"/")
+	buf, ok := r.logs[jobName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("job %q is not found", jobName), http.StatusNotFound)
+		return
+	}
+
+	since, tail, err := parseLogQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range buf.Lines(since, tail) {
+		fmt.Fprintln(w, line.Text)
+	}
+}
+
+// parseLogQuery parses the "since" and "tail" query parameters.
+func parseLogQuery(req *http.Request) (time.Time, int, error) {
+	query := req.URL.Query()
+	tail := 0
+	if v := query.Get("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return time.Time{}, 0, errors.New("tail must be a non-negative integer")
+		}
+		tail = n
+	}
+	var since time.Time
+	if v := query.Get("since"); v != "" {
+		t, err := parseSince(v, time.Now())
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		since = t
+	}
+	return since, tail, nil
+}
+
+// parseSince parses the value of the "since" query parameter.
+// The value is either an RFC3339 timestamp or a duration relative to now, such as "10m".
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("since must be an RFC3339 timestamp or a non-negative duration: %s", v)
+	}
+	return now.Add(-d), nil
+}
@@ -1,12 +1,17 @@
 package entrypoint
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/cybozu-go/nyamber/pkg/constants"
 	"github.com/go-logr/logr"
@@ -14,6 +19,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/zap"
+	"k8s.io/utils/ptr"
 )
 
 const apiAddr = "localhost"
@@ -110,7 +116,26 @@ var _ = Describe("entrypoint status API test", func() {
 				},
 			},
 			{
-				name: "first command is fail and second one is pending",
+				name: "one command which exceeds its timeout",
+				input: []Job{
+					{
+						Name:    "test9",
+						Command: "sh",
+						Args:    []string{"-c", "sleep 60 & sleep 60"},
+						Timeout: 2 * time.Second,
+					},
+					{
+						Name:    "test10",
+						Command: "true",
+						Args:    []string{},
+					}},
+				expected: []statusResponse{
+					{Jobs: []job{{Name: "test9", Status: "Running"}, {Name: "test10", Status: "Pending"}}},
+					{Jobs: []job{{Name: "test9", Status: "TimedOut"}, {Name: "test10", Status: "Skipped"}}},
+				},
+			},
+			{
+				name: "first command is fail and second one is skipped",
 				input: []Job{
 					{
 						Name:    "test7",
@@ -123,7 +148,74 @@ var _ = Describe("entrypoint status API test", func() {
 						Args:    []string{"5"},
 					}},
 				expected: []statusResponse{
-					{Jobs: []job{{Name: "test7", Status: "Failed"}, {Name: "test8", Status: "Pending"}}},
+					{Jobs: []job{{Name: "test7", Status: "Failed"}, {Name: "test8", Status: "Skipped"}}},
+				},
+			},
+			{
+				name: "jobs which depend on the same job run in parallel",
+				input: []Job{
+					{
+						Name:    "test11",
+						Command: "sleep",
+						Args:    []string{"1"},
+					},
+					{
+						Name:    "test12",
+						Command: "sleep",
+						Args:    []string{"3"},
+					},
+					{
+						Name:      "test13",
+						Command:   "sleep",
+						Args:      []string{"3"},
+						DependsOn: []string{"test11"},
+					},
+					{
+						Name:      "test14",
+						Command:   "true",
+						Args:      []string{},
+						DependsOn: []string{"test12", "test13"},
+					}},
+				expected: []statusResponse{
+					{Jobs: []job{{Name: "test11", Status: "Completed"}, {Name: "test12", Status: "Running"}, {Name: "test13", Status: "Running"}, {Name: "test14", Status: "Pending"}}},
+					{Jobs: []job{{Name: "test11", Status: "Completed"}, {Name: "test12", Status: "Completed"}, {Name: "test13", Status: "Completed"}, {Name: "test14", Status: "Completed"}}},
+				},
+			},
+			{
+				name: "jobs which depend on a failed job are skipped",
+				input: []Job{
+					{
+						Name:      "test15",
+						Command:   "false",
+						Args:      []string{},
+						DependsOn: []string{},
+					},
+					{
+						Name:      "test16",
+						Command:   "sleep",
+						Args:      []string{"1"},
+						DependsOn: []string{},
+					},
+					{
+						Name:      "test17",
+						Command:   "true",
+						Args:      []string{},
+						DependsOn: []string{"test15"},
+					},
+					{
+						Name:      "test18",
+						Command:   "true",
+						Args:      []string{},
+						DependsOn: []string{"test17"},
+					},
+					{
+						Name:      "test19",
+						Command:   "true",
+						Args:      []string{},
+						DependsOn: []string{"test16"},
+					}},
+				expected: []statusResponse{
+					{Jobs: []job{{Name: "test15", Status: "Failed"}, {Name: "test16", Status: "Completed"}, {Name: "test17", Status: "Skipped"}, {Name: "test18", Status: "Skipped"}, {Name: "test19", Status: "Completed"}}},
 				},
 			},
 		}
@@ -141,9 +233,352 @@ var _ = Describe("entrypoint status API test", func() {
 	})
 })
 
+var _ = Describe("entrypoint job dependency test", func() {
+	It("should reject invalid dependencies", func() {
+		testCases := []struct {
+			name  string
+			input []Job
+		}{
+			{
+				name: "duplicated job names",
+				input: []Job{
+					{Name: "a", Command: "true"},
+					{Name: "a", Command: "true"},
+				},
+			},
+			{
+				name: "unknown dependency",
+				input: []Job{
+					{Name: "a", Command: "true", DependsOn: []string{"b"}},
+				},
+			},
+			{
+				name: "self dependency",
+				input: []Job{
+					{Name: "a", Command: "true", DependsOn: []string{"a"}},
+				},
+			},
+			{
+				name: "dependency on finally job",
+				input: []Job{
+					{Name: "a", Command: "true", Finally: true},
+					{Name: "b", Command: "true", DependsOn: []string{"a"}},
+				},
+			},
+			{
+				name: "dependency cycle",
+				input: []Job{
+					{Name: "a", Command: "true", DependsOn: []string{"c"}},
+					{Name: "b", Command: "true"},
+					{Name: "c", Command: "true"},
+				},
+			},
+		}
+		for _, tt := range testCases {
+			By(tt.name)
+			_, err := NewRunner("localhost:0", log, tt.input, RunnerOptions{})
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
+
+var _ = Describe("entrypoint finally job test", func() {
+	It("should run finally jobs after the other jobs", func() {
+		cancel := startRunner([]Job{
+			{Name: "cleanup1", Command: "sh", Args: []string{"-c", "exit 1"}, Finally: true},
+			{Name: "test1", Command: "sh", Args: []string{"-c", "sleep 1; exit 1"}},
+			{Name: "test2", Command: "true", Args: []string{}},
+			{Name: "test3", Command: "sleep", Args: []string{"2"}, DependsOn: []string{}},
+			{Name: "cleanup2", Command: "true", Args: []string{}, Finally: true},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+
+		By("checking finally jobs wait for all the other jobs")
+		Eventually(getFullStatus, 10, 0.1).Should(HaveField("Jobs", HaveLen(5)))
+		Consistently(func(g Gomega) {
+			resp, err := getFullStatus()
+			g.Expect(err).NotTo(HaveOccurred())
+			if resp.Jobs[3].Status == JobStatusRunning {
+				g.Expect(resp.Jobs[0].Status).To(Equal(JobStatusPending))
+				g.Expect(resp.Jobs[4].Status).To(Equal(JobStatusPending))
+			}
+		}, 2, 0.2).Should(Succeed())
+
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{
+				{Name: "cleanup1", Status: "Failed"},
+				{Name: "test1", Status: "Failed"},
+				{Name: "test2", Status: "Skipped"},
+				{Name: "test3", Status: "Completed"},
+				{Name: "cleanup2", Status: "Completed"},
+			},
+		}))
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Finally).To(BeTrue())
+		Expect(resp.Jobs[1].Finally).To(BeFalse())
+		Expect(resp.Jobs[0].StartTime >= resp.Jobs[3].EndTime).To(BeTrue())
+		Expect(resp.Jobs[4].StartTime >= resp.Jobs[0].EndTime).To(BeTrue())
+	})
+})
+
+var _ = Describe("entrypoint retry test", func() {
+	It("should retry a failed job", func() {
+		marker := filepath.Join(GinkgoT().TempDir(), "marker")
+		cancel := startRunner([]Job{
+			{
+				Name:         "test1",
+				Command:      "sh",
+				Args:         []string{"-c", fmt.Sprintf("test -f %[1]s && exit 0; touch %[1]s; exit 1", marker)},
+				Retries:      2,
+				RetryBackoff: time.Second,
+			},
+			{
+				Name:         "test2",
+				Command:      "false",
+				Args:         []string{},
+				Retries:      1,
+				RetryBackoff: time.Second,
+			},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+
+		var resp *StatusResponse
+		Eventually(func(g Gomega) {
+			var err error
+			resp, err = getFullStatus()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(resp.Jobs).To(HaveLen(2))
+			g.Expect(resp.Jobs[1].Status).To(Equal(JobStatusFailed))
+		}, 10, 0.5).Should(Succeed())
+
+		By("checking the first job succeeded on the second attempt")
+		Expect(resp.Jobs[0].Status).To(Equal(JobStatusCompleted))
+		Expect(resp.Jobs[0].Attempt).To(Equal(2))
+		Expect(resp.Jobs[0].Attempts).To(HaveLen(2))
+		Expect(resp.Jobs[0].Attempts[0].Status).To(Equal(JobStatusFailed))
+		Expect(resp.Jobs[0].Attempts[1].Status).To(Equal(JobStatusCompleted))
+
+		By("checking the second job failed after retries")
+		Expect(resp.Jobs[1].Attempt).To(Equal(2))
+		Expect(resp.Jobs[1].Attempts).To(HaveLen(2))
+		Expect(resp.Jobs[1].Attempts[0].Status).To(Equal(JobStatusFailed))
+		Expect(resp.Jobs[1].Attempts[1].Status).To(Equal(JobStatusFailed))
+	})
+
+	It("should clear the progress of the failed attempt", func() {
+		dir := GinkgoT().TempDir()
+		marker := filepath.Join(dir, "marker")
+		failure := filepath.Join(dir, "failure")
+		cancel := startRunner([]Job{
+			{
+				Name:    "test1",
+				Command: "sh",
+				Args: []string{"-c", fmt.Sprintf(
+					"test -f %[1]s && exec sleep 30; touch %[1]s; while ! test -f %[2]s; do sleep 0.1; done; exit 1", marker, failure)},
+				Retries:      1,
+				RetryBackoff: time.Second,
+			},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(marker, 10, 0.5).Should(BeAnExistingFile())
+
+		By("reporting the progress in the first attempt")
+		runnerURL := "http://" + net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort))
+		Expect(ReportProgress(runnerURL, "", "test1", Progress{Step: "deploy", Percent: ptr.To(50)})).To(Succeed())
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Step).To(Equal("deploy"))
+
+		By("checking the progress is cleared in the second attempt")
+		Expect(os.WriteFile(failure, nil, 0644)).To(Succeed())
+		Eventually(func(g Gomega) {
+			resp, err := getFullStatus()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(resp.Jobs[0].Attempt).To(Equal(2))
+			g.Expect(resp.Jobs[0].Status).To(Equal(JobStatusRunning))
+			g.Expect(resp.Jobs[0].Step).To(BeEmpty())
+			g.Expect(resp.Jobs[0].Percent).To(BeNil())
+		}, 10, 0.5).Should(Succeed())
+	})
+})
+
+var _ = Describe("entrypoint failure detail test", func() {
+	It("should report the exit code, the signal and the message of jobs", func() {
+		cancel := startRunner([]Job{
+			{Name: "success", Command: "true", Args: []string{}, DependsOn: []string{}},
+			{Name: "exit", Command: "sh", Args: []string{"-c", "exit 3"}, DependsOn: []string{}},
+			{Name: "killed", Command: "sh", Args: []string{"-c", "kill -KILL $$"}, DependsOn: []string{}},
+			{Name: "notfound", Command: "/nonexistent", Args: []string{}, DependsOn: []string{}},
+			{Name: "timeout", Command: "sleep", Args: []string{"10"}, Timeout: time.Second, DependsOn: []string{}},
+			{Name: "skipped", Command: "true", Args: []string{}, DependsOn: []string{"exit"}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+
+		var resp *StatusResponse
+		Eventually(func(g Gomega) {
+			var err error
+			resp, err = getFullStatus()
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, job := range resp.Jobs {
+				g.Expect(isFinished(job.Status)).To(BeTrue(), job.Name)
+			}
+		}, 10, 0.5).Should(Succeed())
+
+		Expect(resp.Jobs[0].Status).To(Equal(JobStatusCompleted))
+		Expect(resp.Jobs[0].ExitCode).To(HaveValue(Equal(0)))
+		Expect(resp.Jobs[0].Message).To(BeEmpty())
+
+		Expect(resp.Jobs[1].Status).To(Equal(JobStatusFailed))
+		Expect(resp.Jobs[1].ExitCode).To(HaveValue(Equal(3)))
+		Expect(resp.Jobs[1].Signal).To(BeEmpty())
+		Expect(resp.Jobs[1].Message).To(Equal("exit code 3"))
+		Expect(resp.Jobs[1].Attempts[0].ExitCode).To(HaveValue(Equal(3)))
+
+		Expect(resp.Jobs[2].Status).To(Equal(JobStatusFailed))
+		Expect(resp.Jobs[2].ExitCode).To(BeNil())
+		Expect(resp.Jobs[2].Signal).To(Equal("SIGKILL"))
+		Expect(resp.Jobs[2].Message).To(Equal("killed by signal SIGKILL"))
+
+		Expect(resp.Jobs[3].Status).To(Equal(JobStatusFailed))
+		Expect(resp.Jobs[3].ExitCode).To(BeNil())
+		Expect(resp.Jobs[3].Message).To(ContainSubstring("no such file or directory"))
+
+		Expect(resp.Jobs[4].Status).To(Equal(JobStatusTimedOut))
+		Expect(resp.Jobs[4].Signal).To(Equal("SIGTERM"))
+		Expect(resp.Jobs[4].Message).To(Equal("timed out after 1s"))
+
+		Expect(resp.Jobs[5].Status).To(Equal(JobStatusSkipped))
+		Expect(resp.Jobs[5].Message).To(Equal("dependency exit is Failed"))
+	})
+})
+
+var _ = Describe("entrypoint logs API test", func() {
+	It("should return the output of each job", func() {
+		cancel := startRunner([]Job{
+			{
+				Name:    "test1",
+				Command: "sh",
+				Args:    []string{"-c", "echo first; echo second >&2; echo third"},
+			},
+			{
+				Name:    "test2",
+				Command: "sh",
+				Args:    []string{"-c", "echo other"},
+			},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Completed"}},
+		}))
+
+		By("getting all lines")
+		Expect(getLogs("test1", "")).To(Equal("first\nsecond\nthird\n"))
+		Expect(getLogs("test2", "")).To(Equal("other\n"))
+
+		By("getting the last lines")
+		Expect(getLogs("test1", "tail=2")).To(Equal("second\nthird\n"))
+
+		By("getting lines since the given time")
+		Expect(getLogs("test1", "since=10m")).To(Equal("first\nsecond\nthird\n"))
+		future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		Expect(getLogs("test1", "since="+future)).To(BeEmpty())
+
+		By("requesting with invalid parameters")
+		code, err := getLogsStatusCode("unknown", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusNotFound))
+		code, err = getLogsStatusCode("test1", "tail=-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusBadRequest))
+		code, err = getLogsStatusCode("test1", "since=yesterday")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusBadRequest))
+	})
+})
+
+var _ = Describe("entrypoint events API test", func() {
+	It("should stream the output and the state of a job", func() {
+		cancel := startRunner([]Job{
+			{
+				Name:    "test1",
+				Command: "sh",
+				Args:    []string{"-c", "sleep 1; echo first; sleep 1; echo second"},
+			},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(Succeed())
+
+		By("following the events until the job finishes")
+		resp, err := http.Get(fmt.Sprintf("http://%s/%s?job=test1", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.EventsEndPoint))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		var lines []string
+		var statuses []string
+		scanner := bufio.NewScanner(resp.Body)
+		var name string
+		for scanner.Scan() {
+			text := scanner.Text()
+			switch {
+			case strings.HasPrefix(text, "event: "):
+				name = strings.TrimPrefix(text, "event: ")
+			case strings.HasPrefix(text, "data: "):
+				data := []byte(strings.TrimPrefix(text, "data: "))
+				switch name {
+				case "log":
+					ev := &LogEvent{}
+					Expect(json.Unmarshal(data, ev)).To(Succeed())
+					Expect(ev.Job).To(Equal("test1"))
+					lines = append(lines, ev.Line)
+				case "status":
+					state := &JobState{}
+					Expect(json.Unmarshal(data, state)).To(Succeed())
+					statuses = append(statuses, state.Status)
+				}
+			}
+		}
+		Expect(scanner.Err()).NotTo(HaveOccurred())
+		Expect(lines).To(Equal([]string{"first", "second"}))
+		Expect(statuses).To(ContainElement("Running"))
+		Expect(statuses[len(statuses)-1]).To(Equal("Completed"))
+
+		By("requesting an unknown job")
+		resp2, err := http.Get(fmt.Sprintf("http://%s/%s?job=unknown", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.EventsEndPoint))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp2.Body.Close()
+		Expect(resp2.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
+
 func startRunner(jobs []Job) context.CancelFunc {
+	return startRunnerWithOptions(jobs, RunnerOptions{})
+}
+
+func startRunnerWithOptions(jobs []Job, options RunnerOptions) context.CancelFunc {
 	ctx, cancel := context.WithCancel(context.Background())
-	runner := NewRunner(net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), log, jobs)
+	runner, err := NewRunner(net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), log, jobs, options)
+	Expect(err).NotTo(HaveOccurred())
 	go func() {
 		defer GinkgoRecover()
 		Expect(runner.Run(ctx)).To(Succeed())
@@ -169,6 +604,52 @@ func getStatus() (*statusResponse, error) {
 	return res, nil
 }
 
+func getFullStatus() (*StatusResponse, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.StatusEndPoint))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	res := &StatusResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func logsURL(jobName, query string) string {
+	u := fmt.Sprintf("http://%s/%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.LogsEndPoint, jobName)
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+func getLogs(jobName, query string) (string, error) {
+	resp, err := http.Get(logsURL(jobName, query))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func getLogsStatusCode(jobName, query string) (int, error) {
+	resp, err := http.Get(logsURL(jobName, query))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
 func connect() error {
 	conn, err := net.Dial("tcp", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)))
 	if err != nil {
@@ -0,0 +1,152 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Policies to handle the jobs which were running when the runner restarted.
+const (
+	// ResumePolicyResume runs the interrupted jobs again from the beginning.
+	ResumePolicyResume = "Resume"
+
+	// ResumePolicyInterrupt marks the interrupted jobs as Interrupted.
+	ResumePolicyInterrupt = "Interrupt"
+)
+
+const (
+	stateFileName      = "state.json"
+	interruptedMessage = "interrupted by a restart of the runner"
+)
+
+// restoreState restores the job states recorded in the state directory.
+// The jobs are matched by name, and the jobs which are not recorded stay Pending.
+// The jobs which were running or terminated by the shutdown of the runner are handled by ResumePolicy,
+// except that the jobs terminated before they started become Pending again.
+func (r *Runner) restoreState() error {
+	if r.options.StateDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(r.options.StateDir, 0755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(r.options.StateDir, stateFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	saved := &StatusResponse{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		return fmt.Errorf("failed to parse the job states in %s: %w", r.options.StateDir, err)
+	}
+
+	for _, state := range saved.Jobs {
+		i, ok := r.jobIndex(state.Name)
+		if !ok {
+			continue
+		}
+		state.Finally = r.jobs[i].Finally
+		if state.Status == JobStatusTerminated && state.Attempt == 0 {
+			state = initialJobState(r.jobs[i])
+		}
+		if state.Status == JobStatusRunning || state.Status == JobStatusTerminated {
+			if r.options.ResumePolicy == ResumePolicyInterrupt {
+				r.logger.Info("mark interrupted job", "job_name", state.Name)
+				state.Status = JobStatusInterrupted
+				state.EndTime = timestamp()
+				state.Message = interruptedMessage
+			} else {
+				r.logger.Info("resume interrupted job", "job_name", state.Name)
+				state = initialJobState(r.jobs[i])
+			}
+		}
+		r.jobStates[i] = state
+	}
+	snapshot := r.snapshotState()
+	if snapshot == nil {
+		return nil
+	}
+	return r.saveState(snapshot)
+}
+
+// stateSnapshot is the job states to be recorded in the state directory.
+type stateSnapshot struct {
+	version uint64
+	data    []byte
+}
+
+// snapshotState takes a snapshot of the job states to record them outside r.mutex.
+// It returns nil if the state directory is not set or the job states cannot be marshaled.
+// The caller must hold r.mutex.
+func (r *Runner) snapshotState() *stateSnapshot {
+	if r.options.StateDir == "" {
+		return nil
+	}
+	data, err := json.Marshal(&StatusResponse{Jobs: r.jobStates})
+	if err != nil {
+		r.logger.Error(err, "failed to marshal the job states")
+		return nil
+	}
+	r.stateVersion++
+	return &stateSnapshot{version: r.stateVersion, data: data}
+}
+
+// snapshotStateIfTransited takes a snapshot of the job states only if the status or the attempt of a job has changed.
+// The other changes, e.g. the progress and the outputs, are frequent and not needed to resume the jobs,
+// so they are recorded with the next transition.
+// The caller must hold r.mutex.
+func (r *Runner) snapshotStateIfTransited(before, after JobState) *stateSnapshot {
+	if before.Status == after.Status && before.Attempt == after.Attempt {
+		return nil
+	}
+	return r.snapshotState()
+}
+
+// saveState writes the snapshot to the state file unless a newer snapshot has been written.
+func (r *Runner) saveState(snapshot *stateSnapshot) error {
+	r.stateFileMutex.Lock()
+	defer r.stateFileMutex.Unlock()
+	if snapshot.version <= r.savedVersion {
+		return nil
+	}
+
+	// Write to a temporary file and rename it so that the state file is never partially written.
+	f, err := os.CreateTemp(r.options.StateDir, stateFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(snapshot.data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(r.options.StateDir, stateFileName)); err != nil {
+		return err
+	}
+	r.savedVersion = snapshot.version
+	return nil
+}
+
+// recordState records the snapshot of the job states and logs the error if any.
+// It does nothing if snapshot is nil. The caller must not hold r.mutex.
+func (r *Runner) recordState(snapshot *stateSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	if err := r.saveState(snapshot); err != nil {
+		r.logger.Error(err, "failed to record the job states", "state_dir", r.options.StateDir)
+	}
+}
@@ -0,0 +1,155 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint state test", func() {
+	const startTime = "2022-06-01T00:00:00Z"
+	jobs := []Job{
+		{Name: "test1", Command: "true", Args: []string{}},
+		{Name: "test2", Command: "true", Args: []string{}},
+		{Name: "test3", Command: "true", Args: []string{}},
+	}
+
+	writeState := func(dir string) {
+		data, err := json.Marshal(&StatusResponse{
+			Jobs: []JobState{
+				{Name: "test1", Status: JobStatusCompleted, StartTime: startTime, EndTime: startTime},
+				{Name: "test2", Status: JobStatusRunning, StartTime: startTime, Attempt: 1},
+				{Name: "test3", Status: JobStatusPending},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, stateFileName), data, 0644)).To(Succeed())
+	}
+
+	readState := func(dir string) (*StatusResponse, error) {
+		data, err := os.ReadFile(filepath.Join(dir, stateFileName))
+		if err != nil {
+			return nil, err
+		}
+		resp := &StatusResponse{}
+		if err := json.Unmarshal(data, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	It("should record the job states", func() {
+		dir := filepath.Join(GinkgoT().TempDir(), "state")
+		cancel := startRunnerWithOptions(jobs, RunnerOptions{StateDir: dir})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Completed"}, {Name: "test3", Status: "Completed"}},
+		}))
+
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readState(dir)).To(Equal(resp))
+	})
+
+	It("should record the job states only on the transitions", func() {
+		dir := filepath.Join(GinkgoT().TempDir(), "state")
+		runner, err := NewRunner(":0", log, jobs, RunnerOptions{StateDir: dir})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("not recording the progress")
+		runner.updateJobState(0, func(state *JobState) {
+			state.Step = "step1"
+		})
+		Expect(filepath.Join(dir, stateFileName)).NotTo(BeAnExistingFile())
+
+		By("recording the progress with the next transition")
+		runner.updateJobState(0, func(state *JobState) {
+			state.Status = JobStatusRunning
+			state.Attempt = 1
+		})
+		resp, err := readState(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].Status).To(Equal(JobStatusRunning))
+		Expect(resp.Jobs[0].Step).To(Equal("step1"))
+	})
+
+	It("should resume the interrupted job", func() {
+		dir := GinkgoT().TempDir()
+		writeState(dir)
+		cancel := startRunnerWithOptions(jobs, RunnerOptions{StateDir: dir, ResumePolicy: ResumePolicyResume})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Completed"}, {Name: "test3", Status: "Completed"}},
+		}))
+
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].StartTime).To(Equal(startTime))
+		Expect(resp.Jobs[1].StartTime).NotTo(Equal(startTime))
+		Expect(readState(dir)).To(Equal(resp))
+	})
+
+	It("should mark the interrupted job", func() {
+		dir := GinkgoT().TempDir()
+		writeState(dir)
+		cancel := startRunnerWithOptions(jobs, RunnerOptions{StateDir: dir, ResumePolicy: ResumePolicyInterrupt})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Interrupted"}, {Name: "test3", Status: "Skipped"}},
+		}))
+
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[1].StartTime).To(Equal(startTime))
+		Expect(resp.Jobs[1].Message).To(Equal(interruptedMessage))
+	})
+
+	It("should apply the resume policy to the jobs terminated by shutdown", func() {
+		dir := GinkgoT().TempDir()
+		writeTerminatedState := func() {
+			data, err := json.Marshal(&StatusResponse{
+				Jobs: []JobState{
+					{Name: "test1", Status: JobStatusCompleted, StartTime: startTime, EndTime: startTime},
+					{Name: "test2", Status: JobStatusTerminated, StartTime: startTime, EndTime: startTime, Attempt: 1, Message: shutdownMessage},
+					{Name: "test3", Status: JobStatusTerminated, Message: shutdownMessage},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(dir, stateFileName), data, 0644)).To(Succeed())
+		}
+
+		By("marking the started job as interrupted")
+		writeTerminatedState()
+		runner, err := NewRunner(":0", log, jobs, RunnerOptions{StateDir: dir, ResumePolicy: ResumePolicyInterrupt})
+		Expect(err).NotTo(HaveOccurred())
+		states := runner.getJobStates()
+		Expect(states[1].Status).To(Equal(JobStatusInterrupted))
+		Expect(states[1].Message).To(Equal(interruptedMessage))
+		Expect(states[2]).To(Equal(JobState{Name: "test3", Status: JobStatusPending}))
+
+		By("resuming the started job")
+		writeTerminatedState()
+		runner, err = NewRunner(":0", log, jobs, RunnerOptions{StateDir: dir, ResumePolicy: ResumePolicyResume})
+		Expect(err).NotTo(HaveOccurred())
+		states = runner.getJobStates()
+		Expect(states[1]).To(Equal(JobState{Name: "test2", Status: JobStatusPending}))
+		Expect(states[2]).To(Equal(JobState{Name: "test3", Status: JobStatusPending}))
+	})
+
+	It("should reject an unknown resume policy", func() {
+		_, err := NewRunner("localhost:0", log, jobs, RunnerOptions{ResumePolicy: "Unknown"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,132 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/gorilla/websocket"
+)
+
+// DefaultTerminalShell is the default shell run by the terminal API.
+const DefaultTerminalShell = "/bin/bash"
+
+// TerminalSize is a message sent by a terminal client to resize the terminal.
+type TerminalSize struct {
+	Cols uint16 `json:"cols"`
+	Rows uint16 `json:"rows"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// The terminal is protected by the token instead of the origin.
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// terminalHandler runs a shell on a pseudo terminal and connects it to a websocket.
+// Binary messages carry the input and the output of the terminal,
+// and a text message from the client carries a TerminalSize in JSON to resize the terminal.
+func (r *Runner) terminalHandler(w http.ResponseWriter, req *http.Request) {
+	if !r.options.Terminal {
+		http.Error(w, "terminal is disabled", http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// Upgrade has already responded with an error.
+		return
+	}
+	defer conn.Close()
+
+	r.logger.Info("terminal session start", "remote_addr", req.RemoteAddr)
+	err = r.runTerminal(req.Context(), conn)
+	if err != nil {
+		r.logger.Error(err, "terminal session error", "remote_addr", req.RemoteAddr)
+	}
+	r.logger.Info("terminal session end", "remote_addr", req.RemoteAddr)
+}
+
+// runTerminal runs a shell connected to conn until the shell exits, the client disconnects or ctx is done.
+func (r *Runner) runTerminal(ctx context.Context, conn *websocket.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.options.terminalShell())
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	// The shell is the leader of a new session, so kill the whole session like a hangup of the terminal.
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	tty, err := pty.Start(cmd)
+	if err != nil {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to start shell"))
+		return err
+	}
+	defer tty.Close()
+
+	// Forward the input from the client. The shell is killed when the client disconnects.
+	go func() {
+		defer cancel()
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch typ {
+			case websocket.BinaryMessage:
+				if _, err := tty.Write(data); err != nil {
+					return
+				}
+			case websocket.TextMessage:
+				size := TerminalSize{}
+				if err := json.Unmarshal(data, &size); err != nil {
+					r.logger.Error(err, "invalid terminal message")
+					continue
+				}
+				pty.Setsize(tty, &pty.Winsize{Cols: size.Cols, Rows: size.Rows})
+			}
+		}
+	}()
+
+	// Forward the output to the client until the shell exits.
+	buf := make([]byte, 4096)
+	for {
+		n, err := tty.Read(buf)
+		if n > 0 {
+			if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+				cancel()
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	err = cmd.Wait()
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+		err = nil
+	}
+	message := "shell exited"
+	if exitCode, signal := exitStatus(cmd.ProcessState); signal != "" {
+		message = "shell killed by signal " + signal
+	} else if exitCode != nil {
+		message = fmt.Sprintf("shell exited with code %d", *exitCode)
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, message), time.Now().Add(time.Second))
+	return err
+}
@@ -0,0 +1,91 @@
+package entrypoint
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cybozu-go/nyamber/pkg/constants"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint terminal test", func() {
+	It("should run a shell for a client with the token", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("secret\n"), 0600)).To(Succeed())
+		cancel := startRunnerWithOptions([]Job{
+			{Name: "test1", Command: "sleep", Args: []string{"30"}},
+		}, RunnerOptions{TokenFile: tokenFile, Terminal: true, TerminalShell: "/bin/sh"})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(func() (int, error) { return getHealthStatusCode(constants.HealthzEndPoint) }, 10, 0.5).Should(Equal(http.StatusOK))
+
+		By("connecting without the token")
+		_, resp, err := dialTerminal("")
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		_, resp, err = dialTerminal("wrong")
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		By("connecting with the token query parameter")
+		u := fmt.Sprintf("ws://%s/%s?token=secret", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.TerminalEndPoint)
+		queryConn, _, err := websocket.DefaultDialer.Dial(u, nil)
+		Expect(err).NotTo(HaveOccurred())
+		queryConn.Close()
+		Expect(requestAPI(http.MethodGet, constants.TerminalEndPoint+"?token=secret", "")).To(Equal(http.StatusUnauthorized))
+
+		By("running commands in the shell")
+		conn, _, err := dialTerminal("secret")
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		Expect(conn.WriteMessage(websocket.TextMessage, []byte(`{"cols": 100, "rows": 40}`))).To(Succeed())
+		Expect(conn.WriteMessage(websocket.BinaryMessage, []byte("stty size; echo $TERM; exit 3\n"))).To(Succeed())
+
+		var output strings.Builder
+		var closeErr *websocket.CloseError
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				Expect(err).To(BeAssignableToTypeOf(closeErr))
+				closeErr = err.(*websocket.CloseError)
+				break
+			}
+			output.Write(data)
+		}
+		Expect(output.String()).To(ContainSubstring("40 100\r\nxterm-256color\r\n"))
+		Expect(closeErr.Code).To(Equal(websocket.CloseNormalClosure))
+		Expect(closeErr.Text).To(Equal("shell exited with code 3"))
+	})
+
+	It("should disable the terminal by default", func() {
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "true", Args: []string{}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(HaveField("Jobs", HaveLen(1)))
+
+		_, resp, err := dialTerminal("")
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
+
+func dialTerminal(token string) (*websocket.Conn, *http.Response, error) {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	u := fmt.Sprintf("ws://%s/%s", net.JoinHostPort(apiAddr, fmt.Sprintf("%d", constants.ListenPort)), constants.TerminalEndPoint)
+	return websocket.DefaultDialer.Dial(u, header)
+}
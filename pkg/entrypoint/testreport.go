@@ -0,0 +1,152 @@
+package entrypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TestReport is the summary of a test report written by a job.
+type TestReport struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+
+	// FailedTests is the names of the failed tests.
+	FailedTests []string `json:"failedTests,omitempty"`
+}
+
+// readTestReport reads the test report of the job written after since.
+// It returns nil if the job has no test report or the report is not written after since.
+func readTestReport(job Job, since time.Time) (*TestReport, error) {
+	if job.TestReport == "" {
+		return nil, nil
+	}
+	path := job.TestReport
+	if !filepath.IsAbs(path) && job.WorkingDir != "" {
+		path = filepath.Join(job.WorkingDir, path)
+	}
+
+	// Ignore the report left by a previous run.
+	// The modification time is compared in seconds because file systems record it with a coarse clock.
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.ModTime().Before(since.Truncate(time.Second))) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTestReport(data)
+}
+
+// parseTestReport parses a JUnit XML report or a Ginkgo JSON report.
+func parseTestReport(data []byte) (*TestReport, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("<")):
+		return parseJUnitReport(data)
+	case bytes.HasPrefix(data, []byte("[")):
+		return parseGinkgoReport(data)
+	}
+	return nil, errors.New("test report must be a JUnit XML or a Ginkgo JSON report")
+}
+
+type junitTestSuite struct {
+	TestSuites []junitTestSuite `xml:"testsuite"`
+	TestCases  []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string    `xml:"name,attr"`
+	Failure *struct{} `xml:"failure"`
+	Error   *struct{} `xml:"error"`
+	Skipped *struct{} `xml:"skipped"`
+}
+
+// parseJUnitReport parses a JUnit XML report whose root element is either <testsuites> or <testsuite>.
+func parseJUnitReport(data []byte) (*TestReport, error) {
+	root := &junitTestSuite{}
+	if err := xml.Unmarshal(data, root); err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+	}
+
+	report := &TestReport{}
+	var walk func(suite *junitTestSuite)
+	walk = func(suite *junitTestSuite) {
+		for _, tc := range suite.TestCases {
+			switch {
+			case tc.Failure != nil || tc.Error != nil:
+				report.Failed++
+				report.FailedTests = append(report.FailedTests, tc.Name)
+			case tc.Skipped != nil:
+				report.Skipped++
+			default:
+				report.Passed++
+			}
+		}
+		for i := range suite.TestSuites {
+			walk(&suite.TestSuites[i])
+		}
+	}
+	walk(root)
+	return report, nil
+}
+
+type ginkgoReport struct {
+	SpecReports []ginkgoSpecReport `json:"SpecReports"`
+}
+
+type ginkgoSpecReport struct {
+	ContainerHierarchyTexts []string `json:"ContainerHierarchyTexts"`
+	LeafNodeType            string   `json:"LeafNodeType"`
+	LeafNodeText            string   `json:"LeafNodeText"`
+	State                   string   `json:"State"`
+}
+
+func (s *ginkgoSpecReport) name() string {
+	if s.LeafNodeText == "" {
+		return s.LeafNodeType
+	}
+	return strings.Join(append(s.ContainerHierarchyTexts, s.LeafNodeText), " ")
+}
+
+// parseGinkgoReport parses a report written by ginkgo --json-report.
+// Only specs are counted, but failed suite nodes such as BeforeSuite are counted as failed tests.
+func parseGinkgoReport(data []byte) (*TestReport, error) {
+	var suites []ginkgoReport
+	if err := json.Unmarshal(data, &suites); err != nil {
+		return nil, fmt.Errorf("failed to parse Ginkgo report: %w", err)
+	}
+
+	report := &TestReport{}
+	for _, suite := range suites {
+		for _, spec := range suite.SpecReports {
+			isSpec := spec.LeafNodeType == "It"
+			switch spec.State {
+			case "passed":
+				if isSpec {
+					report.Passed++
+				}
+			case "skipped", "pending":
+				if isSpec {
+					report.Skipped++
+				}
+			default:
+				report.Failed++
+				report.FailedTests = append(report.FailedTests, spec.name())
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,119 @@
+package entrypoint
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const junitXMLReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="5">
+  <testsuite name="dctest" tests="4">
+    <testcase name="should bootstrap" classname="dctest"></testcase>
+    <testcase name="should sync apps" classname="dctest">
+      <failure message="timed out">app is not synced</failure>
+    </testcase>
+    <testcase name="should upgrade" classname="dctest">
+      <skipped message="skipped"></skipped>
+    </testcase>
+    <testcase name="should reboot" classname="dctest">
+      <error message="panic"></error>
+    </testcase>
+  </testsuite>
+  <testsuite name="other" tests="1">
+    <testcase name="should work" classname="other"></testcase>
+  </testsuite>
+</testsuites>
+`
+
+const ginkgoJSONReport = `[
+  {
+    "SuiteDescription": "dctest",
+    "SpecReports": [
+      {"ContainerHierarchyTexts": null, "LeafNodeType": "BeforeSuite", "LeafNodeText": "", "State": "passed"},
+      {"ContainerHierarchyTexts": ["bootstrap"], "LeafNodeType": "It", "LeafNodeText": "should work", "State": "passed"},
+      {"ContainerHierarchyTexts": ["apps", "argocd"], "LeafNodeType": "It", "LeafNodeText": "should sync", "State": "failed"},
+      {"ContainerHierarchyTexts": ["apps"], "LeafNodeType": "It", "LeafNodeText": "should upgrade", "State": "skipped"},
+      {"ContainerHierarchyTexts": ["apps"], "LeafNodeType": "It", "LeafNodeText": "should be pending", "State": "pending"},
+      {"ContainerHierarchyTexts": null, "LeafNodeType": "AfterSuite", "LeafNodeText": "", "State": "panicked"}
+    ]
+  }
+]
+`
+
+var _ = Describe("entrypoint test report test", func() {
+	It("should parse a JUnit report", func() {
+		report, err := parseTestReport([]byte(junitXMLReport))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(Equal(&TestReport{
+			Passed:      2,
+			Failed:      2,
+			Skipped:     1,
+			FailedTests: []string{"should sync apps", "should reboot"},
+		}))
+
+		By("parsing a report with a single test suite")
+		report, err = parseTestReport([]byte(`<testsuite><testcase name="test1"/></testsuite>`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(Equal(&TestReport{Passed: 1}))
+	})
+
+	It("should parse a Ginkgo report", func() {
+		report, err := parseTestReport([]byte(ginkgoJSONReport))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(Equal(&TestReport{
+			Passed:      1,
+			Failed:      2,
+			Skipped:     2,
+			FailedTests: []string{"apps argocd should sync", "AfterSuite"},
+		}))
+	})
+
+	It("should reject invalid reports", func() {
+		for _, data := range []string{``, `{}`, `<testsuite>`, `[{"SpecReports": {}}]`} {
+			_, err := parseTestReport([]byte(data))
+			Expect(err).To(HaveOccurred(), data)
+		}
+	})
+
+	It("should report the summary of the test report of a job", func() {
+		dir := GinkgoT().TempDir()
+		stale := filepath.Join(dir, "stale.xml")
+		Expect(os.WriteFile(stale, []byte(junitXMLReport), 0644)).To(Succeed())
+		Expect(os.Chtimes(stale, time.Time{}, time.Now().Add(-time.Hour))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "report.json"), []byte(ginkgoJSONReport), 0644)).To(Succeed())
+
+		cancel := startRunner([]Job{
+			{
+				Name:       "test1",
+				Command:    "touch",
+				Args:       []string{"report.json"},
+				WorkingDir: dir,
+				TestReport: "report.json",
+			},
+			{Name: "test2", Command: "true", Args: []string{}, TestReport: stale},
+			{Name: "test3", Command: "true", Args: []string{}, TestReport: filepath.Join(dir, "missing.xml")},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 10, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Completed"}, {Name: "test2", Status: "Completed"}, {Name: "test3", Status: "Completed"}},
+		}))
+
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Jobs[0].TestReport).To(Equal(&TestReport{
+			Passed:      1,
+			Failed:      2,
+			Skipped:     2,
+			FailedTests: []string{"apps argocd should sync", "AfterSuite"},
+		}))
+		Expect(resp.Jobs[1].TestReport).To(BeNil())
+		Expect(resp.Jobs[2].TestReport).To(BeNil())
+	})
+})
@@ -0,0 +1,65 @@
+package entrypoint
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// blockSize is the unit of the block I/O counts in rusage.
+const blockSize = 512
+
+// ResourceUsage is the resource usage of a job.
+// It is gathered from the rusage of the job process when it exits, so it covers the process
+// and its descendants which have exited and been waited for. Processes left running after the job, e.g. daemons, are not covered.
+type ResourceUsage struct {
+	// UserCPUSeconds and SystemCPUSeconds are the CPU time spent in user mode and in kernel mode.
+	UserCPUSeconds   float64 `json:"userCPUSeconds"`
+	SystemCPUSeconds float64 `json:"systemCPUSeconds"`
+
+	// MaxRSSBytes is the maximum resident set size of the process or its largest descendant.
+	MaxRSSBytes int64 `json:"maxRSSBytes"`
+
+	// ReadBytes and WriteBytes are the bytes read from and written to the block devices.
+	ReadBytes  int64 `json:"readBytes"`
+	WriteBytes int64 `json:"writeBytes"`
+}
+
+// resourceUsage returns the resource usage of the exited process.
+// It returns nil if the process did not start.
+func resourceUsage(state *os.ProcessState) *ResourceUsage {
+	if state == nil {
+		return nil
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return nil
+	}
+	return &ResourceUsage{
+		UserCPUSeconds:   time.Duration(ru.Utime.Nano()).Seconds(),
+		SystemCPUSeconds: time.Duration(ru.Stime.Nano()).Seconds(),
+		// Linux reports ru_maxrss in kilobytes.
+		MaxRSSBytes: ru.Maxrss * 1024,
+		ReadBytes:   ru.Inblock * blockSize,
+		WriteBytes:  ru.Oublock * blockSize,
+	}
+}
+
+// addResourceUsage returns the usage of total and usage together.
+// The CPU time and the I/O are summed up, and the maximum RSS is the larger one.
+func addResourceUsage(total, usage *ResourceUsage) *ResourceUsage {
+	if usage == nil {
+		return total
+	}
+	if total == nil {
+		sum := *usage
+		return &sum
+	}
+	return &ResourceUsage{
+		UserCPUSeconds:   total.UserCPUSeconds + usage.UserCPUSeconds,
+		SystemCPUSeconds: total.SystemCPUSeconds + usage.SystemCPUSeconds,
+		MaxRSSBytes:      max(total.MaxRSSBytes, usage.MaxRSSBytes),
+		ReadBytes:        total.ReadBytes + usage.ReadBytes,
+		WriteBytes:       total.WriteBytes + usage.WriteBytes,
+	}
+}
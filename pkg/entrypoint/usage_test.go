@@ -0,0 +1,54 @@
+package entrypoint
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entrypoint resource usage test", func() {
+	It("should sum up the resource usage of attempts", func() {
+		usage := addResourceUsage(nil, nil)
+		Expect(usage).To(BeNil())
+
+		first := &ResourceUsage{UserCPUSeconds: 1.5, SystemCPUSeconds: 0.5, MaxRSSBytes: 2048, ReadBytes: 512, WriteBytes: 1024}
+		usage = addResourceUsage(usage, first)
+		Expect(usage).To(Equal(first))
+		Expect(usage).NotTo(BeIdenticalTo(first))
+
+		usage = addResourceUsage(usage, &ResourceUsage{UserCPUSeconds: 1, SystemCPUSeconds: 1, MaxRSSBytes: 1024, ReadBytes: 512, WriteBytes: 0})
+		Expect(usage).To(Equal(&ResourceUsage{UserCPUSeconds: 2.5, SystemCPUSeconds: 1.5, MaxRSSBytes: 2048, ReadBytes: 1024, WriteBytes: 1024}))
+		Expect(addResourceUsage(usage, nil)).To(BeIdenticalTo(usage))
+	})
+
+	It("should report the resource usage of jobs", func() {
+		cancel := startRunner([]Job{
+			{Name: "test1", Command: "sh", Args: []string{"-c", `i=0; while [ $i -lt 100000 ]; do i=$((i+1)); done; exit 1`}, Retries: 1, RetryBackoff: 1},
+			{Name: "test2", Command: "not-found-command", Args: []string{}, DependsOn: []string{}},
+		})
+		defer func() {
+			cancel()
+			Eventually(func() error { err := connect(); return err }, 10, 0.5).Should(HaveOccurred())
+		}()
+		Eventually(getStatus, 20, 0.5).Should(Equal(&statusResponse{
+			Jobs: []job{{Name: "test1", Status: "Failed"}, {Name: "test2", Status: "Failed"}},
+		}))
+
+		resp, err := getFullStatus()
+		Expect(err).NotTo(HaveOccurred())
+		attempts := resp.Jobs[0].Attempts
+		Expect(attempts).To(HaveLen(2))
+		for _, attempt := range attempts {
+			Expect(attempt.ResourceUsage).NotTo(BeNil())
+			Expect(attempt.ResourceUsage.UserCPUSeconds + attempt.ResourceUsage.SystemCPUSeconds).To(BeNumerically(">", 0))
+			Expect(attempt.ResourceUsage.MaxRSSBytes).To(BeNumerically(">", 0))
+		}
+		usage := resp.Jobs[0].ResourceUsage
+		Expect(usage).NotTo(BeNil())
+		Expect(usage.UserCPUSeconds).To(BeNumerically("~", attempts[0].ResourceUsage.UserCPUSeconds+attempts[1].ResourceUsage.UserCPUSeconds, 1e-9))
+		Expect(usage.MaxRSSBytes).To(Equal(max(attempts[0].ResourceUsage.MaxRSSBytes, attempts[1].ResourceUsage.MaxRSSBytes)))
+
+		By("reporting no resource usage for a command which did not start")
+		Expect(resp.Jobs[1].Attempts[0].ResourceUsage).To(BeNil())
+		Expect(resp.Jobs[1].ResourceUsage).To(BeNil())
+	})
+})
@@ -0,0 +1,88 @@
+// Package resources handles the compute resources of the runner container.
+package resources
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Merge returns the resources in base overridden by the resources in override for each resource name.
+func Merge(base, override corev1.ResourceRequirements) corev1.ResourceRequirements {
+	merged := *base.DeepCopy()
+	merged.Requests = mergeList(merged.Requests, override.Requests)
+	merged.Limits = mergeList(merged.Limits, override.Limits)
+	return merged
+}
+
+func mergeList(base, override corev1.ResourceList) corev1.ResourceList {
+	if len(override) == 0 {
+		return base
+	}
+	if base == nil {
+		base = corev1.ResourceList{}
+	}
+	for name, quantity := range override {
+		base[name] = quantity.DeepCopy()
+	}
+	return base
+}
+
+// Validate checks that the requests do not exceed the limits, and that neither exceeds max.
+// It returns the descriptions of the violations, which are empty if the resources are valid.
+func Validate(resources corev1.ResourceRequirements, max corev1.ResourceList) []string {
+	var violations []string
+	for _, name := range sortedNames(resources.Requests) {
+		request := resources.Requests[name]
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			violations = append(violations, fmt.Sprintf("request of %s (%s) exceeds the limit (%s)", name, request.String(), limit.String()))
+		}
+	}
+	for _, kind := range []struct {
+		name string
+		list corev1.ResourceList
+	}{
+		{"request", resources.Requests},
+		{"limit", resources.Limits},
+	} {
+		for _, name := range sortedNames(kind.list) {
+			quantity := kind.list[name]
+			if m, ok := max[name]; ok && quantity.Cmp(m) > 0 {
+				violations = append(violations, fmt.Sprintf("%s of %s (%s) exceeds the maximum (%s)", kind.name, name, quantity.String(), m.String()))
+			}
+		}
+	}
+	return violations
+}
+
+func sortedNames(list corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Parse parses a list of resources formatted as NAME=QUANTITY separated by commas, e.g. "cpu=16,memory=64Gi".
+func Parse(s string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	if s == "" {
+		return list, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("resource must be formatted as NAME=QUANTITY: %s", item)
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity of %s: %w", name, err)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
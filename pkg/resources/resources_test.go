@@ -0,0 +1,94 @@
+package resources
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("resources", func() {
+	It("should merge resources for each resource name", func() {
+		base := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		}
+		merged := Merge(base, corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+				corev1.ResourceCPU:    resource.MustParse("4"),
+			},
+		})
+		Expect(merged).To(Equal(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+		}))
+		Expect(base.Requests).To(HaveKeyWithValue(corev1.ResourceMemory, resource.MustParse("4Gi")))
+
+		By("merging into empty resources")
+		merged = Merge(corev1.ResourceRequirements{}, corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		})
+		Expect(merged).To(Equal(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		}))
+		Expect(Merge(corev1.ResourceRequirements{}, corev1.ResourceRequirements{})).To(Equal(corev1.ResourceRequirements{}))
+	})
+
+	It("should validate resources", func() {
+		max := corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("8"),
+			corev1.ResourceMemory: resource.MustParse("32Gi"),
+		}
+		Expect(Validate(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+		}, max)).To(BeEmpty())
+		Expect(Validate(corev1.ResourceRequirements{}, nil)).To(BeEmpty())
+
+		Expect(Validate(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("16Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("16"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+		}, max)).To(Equal([]string{
+			"request of memory (16Gi) exceeds the limit (8Gi)",
+			"limit of cpu (16) exceeds the maximum (8)",
+		}))
+		Expect(Validate(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Gi")},
+		}, max)).To(Equal([]string{
+			"request of memory (64Gi) exceeds the maximum (32Gi)",
+		}))
+	})
+
+	It("should parse a list of resources", func() {
+		list, err := Parse("cpu=16,memory=64Gi")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(Equal(corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("16"),
+			corev1.ResourceMemory: resource.MustParse("64Gi"),
+		}))
+
+		list, err = Parse("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(BeEmpty())
+
+		for _, s := range []string{"cpu", "=1", "cpu=abc", "cpu=1,"} {
+			_, err := Parse(s)
+			Expect(err).To(HaveOccurred(), s)
+		}
+	})
+})